@@ -0,0 +1,118 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/filecoin-project/go-address"
+	bls "github.com/kilic/bls12-381"
+)
+
+// Filecoin uses the BLS12-381 "minimal public key size" scheme: public keys in G1, signatures in G2,
+// hashed to the curve with the following domain separation tag (same as filecoin-ffi)
+var blsDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_")
+
+// blsPrivateKey decodes a BLS private key, serialized as a little endian scalar like Lotus does
+func blsPrivateKey(sk []byte) (*bls.Fr, error) {
+	if len(sk) != address.BlsPrivateKeyBytes {
+		return nil, fmt.Errorf("invalid BLS private key length %d", len(sk))
+	}
+
+	be := make([]byte, len(sk))
+	for i := range sk {
+		be[len(sk)-1-i] = sk[i]
+	}
+
+	if new(big.Int).SetBytes(be).Cmp(bls.NewG1().Q()) >= 0 {
+		return nil, fmt.Errorf("invalid BLS private key")
+	}
+
+	scalar := bls.NewFr().FromBytes(be)
+	if scalar.IsZero() {
+		return nil, fmt.Errorf("invalid BLS private key")
+	}
+
+	return scalar, nil
+}
+
+// blsPublicKey returns the compressed public key of a BLS private key
+func blsPublicKey(sk []byte) ([]byte, error) {
+	scalar, err := blsPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	g1 := bls.NewG1()
+	pk := g1.MulScalar(g1.New(), g1.One(), scalar)
+	return g1.ToCompressed(pk), nil
+}
+
+func signBLS(msg []byte, sk []byte) ([]byte, error) {
+	scalar, err := blsPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	g2 := bls.NewG2()
+	point, err := g2.HashToCurve(msg, blsDST)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := g2.MulScalar(g2.New(), point, scalar)
+	return g2.ToCompressed(sig), nil
+}
+
+func verifyBLS(sig []byte, a address.Address, msg []byte) error {
+	if a.Protocol() != address.BLS {
+		return fmt.Errorf("address %s is not a BLS address", a)
+	}
+
+	g1 := bls.NewG1()
+	pk, err := g1.FromCompressed(a.Payload())
+	if err != nil {
+		return err
+	}
+	if g1.IsZero(pk) || !g1.InCorrectSubgroup(pk) {
+		return fmt.Errorf("invalid BLS public key")
+	}
+
+	g2 := bls.NewG2()
+	s, err := g2.FromCompressed(sig)
+	if err != nil {
+		return err
+	}
+	if !g2.InCorrectSubgroup(s) {
+		return fmt.Errorf("invalid BLS signature")
+	}
+
+	point, err := g2.HashToCurve(msg, blsDST)
+	if err != nil {
+		return err
+	}
+
+	// e(pk, H(msg)) == e(g1, sig)
+	engine := bls.NewEngine()
+	engine.AddPair(pk, point)
+	engine.AddPairInv(g1.One(), s)
+	if !engine.Check() {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const blsTestSecretKey = "2a3c5b6f2e1d7a8b9c0d1e2f3a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c06"
+
+func blsTestKeys(t *testing.T) ([]byte, []byte, string) {
+	sk, err := hex.DecodeString(blsTestSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := blsPublicKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	addr, err := r.DeriveFromPublicKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sk, pk, addr
+}
+
+// Known answer of Lotus (lib/sigs/bls on blst) for blsTestSecretKey, signing "rosetta-filecoin-lib"
+const (
+	lotusBLSPublicKey = "95a891ef8ba0ba004734d366bd8456d1a7f712571b0d71759fd4d9a448105205949f6579e473b3b0dc4759bc8c68aedc"
+	lotusBLSAddress   = "swujd34luc5aarzu2ntl3bcw2gt7oesxdmgxc5m72tm2isaqkiczjh3fphshhm5q3rdvtpemncxnz4ypcyuq"
+	lotusBLSSignature = "8a2ebd85f7c143a0b245425376d50b289fa526ed89c5103c72252aaca31c10d5183bcdd3f92fcaa668f73d8ab37e5cf1" +
+		"084c218b1aae3aa00be23edc7b7aa65b3cf6b3c77a9b758ba85d2fffc84c934b8211a385e66676395ccba09909036d28"
+)
+
+func TestBLSKnownAnswer(t *testing.T) {
+	sk, pk, addr := blsTestKeys(t)
+
+	// The private key is a little endian scalar
+	if hex.EncodeToString(pk) != lotusBLSPublicKey {
+		t.Errorf("unexpected public key %x", pk)
+	}
+
+	if addr != "t3"+lotusBLSAddress {
+		t.Errorf("unexpected testnet address %s", addr)
+	}
+
+	mainnet := &RosettaConstructionFilecoin{Mainnet: true}
+	if addr, err := mainnet.DeriveFromPublicKey(pk); err != nil || addr != "f3"+lotusBLSAddress {
+		t.Errorf("unexpected mainnet address %s %v", addr, err)
+	}
+
+	// BLS signatures are deterministic, the hash to the curve depends on the domain separation tag
+	message := []byte("rosetta-filecoin-lib")
+	sig, err := mainnet.SignWithType(message, sk, crypto.SigTypeBLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(sig) != lotusBLSSignature {
+		t.Errorf("unexpected signature %x", sig)
+	}
+
+	lotusSig, _ := hex.DecodeString(lotusBLSSignature)
+	if err := mainnet.Verify(message, pk, lotusSig); err != nil {
+		t.Errorf("the Lotus signature should verify: %v", err)
+	}
+}
+
+func TestDeriveFromBLSPublicKey(t *testing.T) {
+	_, pk, addr := blsTestKeys(t)
+
+	if len(pk) != address.BlsPublicKeyBytes {
+		t.Fatalf("unexpected public key length %d", len(pk))
+	}
+
+	if addr[:2] != "t3" {
+		t.Errorf("expected a BLS address, got %s", addr)
+	}
+}
+
+func TestSignVerifyBLS(t *testing.T) {
+	sk, pk, _ := blsTestKeys(t)
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	message := []byte("rosetta-filecoin-lib")
+
+	sig, err := r.SignWithType(message, sk, crypto.SigTypeBLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Verify(message, pk, sig); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+
+	if err := r.Verify([]byte("tampered"), pk, sig); err == nil {
+		t.Error("signature of another message should not verify")
+	}
+
+	_, otherPk, _ := blsTestKeys(t)
+	otherPk[len(otherPk)-1] ^= 0x01
+	if err := r.Verify(message, otherPk, sig); err == nil {
+		t.Error("signature should not verify with another key")
+	}
+
+	if _, err := r.SignWithType(message, make([]byte, 32), crypto.SigTypeBLS); err == nil {
+		t.Error("zero private key should be rejected")
+	}
+}
+
+func TestSignTxBLS(t *testing.T) {
+	sk, _, addr := blsTestKeys(t)
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+
+	pr := &PaymentRequest{
+		From:     addr,
+		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: TxMetadata{
+			Nonce:      1,
+			GasFeeCap:  abi.NewTokenAmount(1),
+			GasPremium: abi.NewTokenAmount(1),
+			GasLimit:   25000,
+		},
+	}
+
+	unsignedTx, err := r.ConstructPayment(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedTx, err := r.SignTx(unsignedTx, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedJSON, err := base64.StdEncoding.DecodeString(signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sm types.SignedMessage
+	err = json.Unmarshal(signedJSON, &sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sm.Signature.Type != crypto.SigTypeBLS {
+		t.Fatalf("expected a BLS signature, got type %d", sm.Signature.Type)
+	}
+
+	if err := verifyBLS(sm.Signature.Data, sm.Message.From, sm.Message.Cid().Bytes()); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+
+	// BLS signed messages are identified by the cid of the unsigned message
+	hash, err := r.Hash(signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != sm.Message.Cid().String() {
+		t.Errorf("unexpected hash %s", hash)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(unsignedTx)
+	var msg types.Message
+	_ = json.Unmarshal(raw, &msg)
+	msg.From, _ = address.NewIDAddress(1001)
+	raw, _ = json.Marshal(&msg)
+	if _, err := r.SignTx(base64.StdEncoding.EncodeToString(raw), sk); err == nil {
+		t.Error("signing for an ID address should fail")
+	}
+}
@@ -1,7 +1,18 @@
 package rosettaFilecoinLib
 
+import (
+	"encoding/json"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+)
+
+// RosettaConstructionTool defines the construction API
+// Transactions (signed or not) are always exchanged as base64 strings of their Lotus JSON or CBOR serialization,
+// see EncodingFormat. Every method accepts both serializations, so the output of a step is a valid input to the next one.
 type RosettaConstructionTool interface {
-	// DeriveFromPublicKey defines the function to derive the address from an public key (secp256k1)
+	// DeriveFromPublicKey defines the function to derive the address from an public key (secp256k1 or BLS) for the configured network
+	// BLS public keys are recognized by their length (48 bytes)
 	// @return
 	//   - derivedAddress [string]
 	//   - error when deriving address from the public key
@@ -13,7 +24,14 @@ type RosettaConstructionTool interface {
 	//   - error when signing a message
 	Sign(message []byte, sk []byte) ([]byte, error)
 
-	// Verify defines the function to verify the signature of an arbitrary message with the public key (secp256k1)
+	// SignWithType defines the function to sign an arbitrary message with a secret key of the given type (secp256k1 or BLS)
+	// @sigType [crypto.SigType] type of the secret key
+	// @return
+	//   - signature [[]byte] the signature after the message is signed with the private key
+	//   - error when signing a message
+	SignWithType(message []byte, sk []byte, sigType crypto.SigType) ([]byte, error)
+
+	// Verify defines the function to verify the signature of an arbitrary message with the public key (secp256k1 or BLS)
 	// @return
 	//   - error if invalid signature
 	Verify(message []byte, publicKey []byte, signature []byte) error
@@ -34,61 +52,307 @@ type RosettaConstructionTool interface {
 	// @return
 	//   - unsignedTx [string] base64 encoded unsigned transaction
 	//   - error while constructing the multisig SwapAuthorizedParty call
-	ConstructSwapAuthorizedParty(request *MultisigPaymentRequest) (string, error)
+	ConstructSwapAuthorizedParty(request *SwapAuthorizedPartyRequest) (string, error)
+
+	// ConstructAddSigner creates transaction for a multisig AddSigner call, optionally increasing the threshold
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig AddSigner call
+	ConstructAddSigner(request *AddSignerRequest) (string, error)
+
+	// ConstructRemoveSigner creates transaction for a multisig RemoveSigner call, optionally decreasing the threshold
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig RemoveSigner call
+	ConstructRemoveSigner(request *RemoveSignerRequest) (string, error)
+
+	// ConstructChangeNumApprovalsThreshold creates transaction for a multisig ChangeNumApprovalsThreshold call
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig ChangeNumApprovalsThreshold call
+	ConstructChangeNumApprovalsThreshold(request *ChangeNumApprovalsThresholdRequest) (string, error)
+
+	// ConstructLockBalance creates transaction for a multisig LockBalance call, locking Amount of the multisig balance
+	// to unlock linearly over UnlockDuration epochs from StartEpoch. It can only succeed on a multisig without vesting,
+	// see MultisigVesting to check the spendable balance of a vesting multisig.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig LockBalance call
+	ConstructLockBalance(request *LockBalanceRequest) (string, error)
+
+	// ConstructMultisigCreate creates transaction for an Init actor Exec call, creating a multisig funded with Quantity
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig creation
+	ConstructMultisigCreate(request *MultisigCreateRequest) (string, error)
+
+	// ConstructMultisigApprove creates transaction for a multisig Approve call, approving a pending proposal
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig Approve call
+	ConstructMultisigApprove(request *MultisigApproveRequest) (string, error)
+
+	// ConstructMultisigCancel creates transaction for a multisig Cancel call, cancelling a pending proposal (proposer only)
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig Cancel call
+	ConstructMultisigCancel(request *MultisigCancelRequest) (string, error)
+
+	// ComputeProposalHash computes the hash of the transaction proposed by a multisig Propose message, as checked by
+	// Approve and Cancel, so that a signer does not approve another transaction that was given the same ID.
+	// The multisig actor records the proposer by its ID address: requester must be the ID address of the From address
+	// of the proposal (Lotus StateLookupID), it may be empty when From already is an ID address.
+	// @tx [string] signed or unsigned Propose transaction, base64 encoded (JSON or CBOR) or plain JSON
+	// @requester [string] ID address of the proposer
+	// @return
+	//   - proposalHash [[]byte] blake2b-256 hash of the proposal
+	//   - error when tx is not a multisig proposal
+	ComputeProposalHash(tx string, requester string) ([]byte, error)
+
+	// ConstructMultisigApproveProposal creates transaction for a multisig Approve call of the given proposal,
+	// embedding its proposal hash so the call fails if the pending transaction differs
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig Approve call
+	ConstructMultisigApproveProposal(request *MultisigProposalRequest) (string, error)
+
+	// ConstructMultisigCancelProposal creates transaction for a multisig Cancel call of the given proposal,
+	// embedding its proposal hash so the call fails if the pending transaction differs
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the multisig Cancel call
+	ConstructMultisigCancelProposal(request *MultisigProposalRequest) (string, error)
+
+	// ConstructMinerWithdrawBalance creates transaction for a miner WithdrawBalance call, sent by the owner or,
+	// when Multisig is set, proposed on the owner multisig
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the miner WithdrawBalance call
+	ConstructMinerWithdrawBalance(request *MinerWithdrawBalanceRequest) (string, error)
+
+	// ConstructMinerChangeWorkerAddress creates transaction for a miner ChangeWorkerAddress call, replacing the worker
+	// and control addresses. It is sent by the owner or, when Multisig is set, proposed on the owner multisig.
+	// The miner actor of this network version has no ChangeOwnerAddress method, the owner cannot be changed.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the miner ChangeWorkerAddress call
+	ConstructMinerChangeWorkerAddress(request *MinerChangeWorkerAddressRequest) (string, error)
+
+	// ConstructMinerChangePeerID creates transaction for a miner ChangePeerID call, sent by the owner or worker or,
+	// when Multisig is set, proposed on the owner multisig
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the miner ChangePeerID call
+	ConstructMinerChangePeerID(request *MinerChangePeerIDRequest) (string, error)
+
+	// ConstructMarketAddBalance creates transaction for a storage market AddBalance call, depositing Quantity
+	// into the escrow of a provider or client
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the market AddBalance call
+	ConstructMarketAddBalance(request *MarketAddBalanceRequest) (string, error)
 
-	// SignTx signs an unsignedTx using the secret key (secp256k1) and return a signedTx that can be submitted to the node
+	// ConstructMarketWithdrawBalance creates transaction for a storage market WithdrawBalance call, withdrawing
+	// from the escrow of a provider or client
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the market WithdrawBalance call
+	ConstructMarketWithdrawBalance(request *MarketWithdrawBalanceRequest) (string, error)
+
+	// ConstructPaychCreate creates transaction for the creation of a payment channel from From to To, through the
+	// Init actor, funded with Quantity
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the payment channel creation
+	ConstructPaychCreate(request *PaychCreateRequest) (string, error)
+
+	// CreateVoucher creates an unsigned payment channel voucher, to be signed by the From address of the channel
+	// @return
+	//   - voucher [string] base64url encoded voucher, as encoded by Lotus
+	//   - error while creating the voucher
+	CreateVoucher(request *VoucherRequest) (string, error)
+
+	// SignVoucher signs a voucher with the private key of the From address of the channel
+	// @return
+	//   - signedVoucher [string] base64url encoded signed voucher
+	//   - error while signing the voucher
+	SignVoucher(voucher string, sk []byte, sigType crypto.SigType) (string, error)
+
+	// VerifyVoucher verifies the signature of a voucher against the From address of the channel, which must be
+	// a key address (not an ID address)
+	// @return
+	//   - error if the voucher is not signed by from
+	VerifyVoucher(voucher string, from string) error
+
+	// ConstructPaychUpdateChannelState creates transaction redeeming a signed voucher on its payment channel
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the UpdateChannelState call
+	ConstructPaychUpdateChannelState(request *PaychUpdateChannelStateRequest) (string, error)
+
+	// ConstructPaychSettle creates transaction starting the settlement of a payment channel
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the Settle call
+	ConstructPaychSettle(request *PaychRequest) (string, error)
+
+	// ConstructPaychCollect creates transaction collecting a settled payment channel, paying the redeemed
+	// amount to To and the rest to From
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the Collect call
+	ConstructPaychCollect(request *PaychRequest) (string, error)
+
+	// ConstructVerifregAddVerifier creates transaction adding a verifier (notary) with a datacap allowance to the
+	// verified registry. It is sent by the root key holder or, when Multisig is set, proposed on the root key multisig.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the AddVerifier call
+	ConstructVerifregAddVerifier(request *VerifregAllowanceRequest) (string, error)
+
+	// ConstructVerifregRemoveVerifier creates transaction removing a verifier from the verified registry. It is sent
+	// by the root key holder or, when Multisig is set, proposed on the root key multisig.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the RemoveVerifier call
+	ConstructVerifregRemoveVerifier(request *VerifregRemoveVerifierRequest) (string, error)
+
+	// ConstructVerifregAddVerifiedClient creates transaction granting a datacap allowance to a client, taken from the
+	// allowance of the verifier. It is sent by the verifier or, when Multisig is set, proposed on the verifier multisig.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the AddVerifiedClient call
+	ConstructVerifregAddVerifiedClient(request *VerifregAllowanceRequest) (string, error)
+
+	// ConstructMethodCall creates transaction for a call of any actor method, for the operations without a dedicated
+	// constructor. The params are either raw CBOR or the JSON of the specs-actors params type of the method
+	// (e.g. {"AmountRequested":"1000"} for the WithdrawBalance method of a miner), encoded by the library.
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error while constructing the call or encoding its params
+	ConstructMethodCall(request *MethodCallRequest) (string, error)
+
+	// ConstructFromOperations creates the transaction described by Rosetta style operations
+	// (two Send operations, or a single Propose, SwapSigner, AddSigner, RemoveSigner, ChangeNumApprovalsThreshold,
+	// LockBalance, CreateMultisig, Approve or Cancel operation)
+	// @operations [[]Operation] operations of the transaction
+	// @metadata [TxMetadata] nonce and gas of the transaction
+	// @return
+	//   - unsignedTx [string] base64 encoded unsigned transaction
+	//   - error when the operations do not describe a supported transaction
+	ConstructFromOperations(operations []Operation, metadata TxMetadata) (string, error)
+
+	// ParseToOperations describes a transaction as Rosetta style operations, the inverse of ConstructFromOperations
+	// @tx [string] signed or unsigned transaction, base64 encoded (JSON or CBOR) or plain JSON
+	// @return
+	//   - operations [[]Operation] operations of the transaction
+	//   - error when the transaction is not supported
+	ParseToOperations(tx string) ([]Operation, error)
+
+	// ConstructSigningPayload returns what an external signer must sign to authorize an unsignedTx
+	// secp256k1 signers sign the blake2b-256 hash of the payload bytes (as Sign does), BLS signers sign the bytes
 	// @unsignedTransaction [string] base64 encoded unsigned transaction
-	// @sk [[]byte] secp256k1 secret key
 	// @return
-	//   - signedTx [string] the signed transaction
+	//   - payload [*SigningPayload] the message CID bytes and the signature type expected from the From address
+	//   - error when decoding the transaction, or when its fees exceed the fee guard
+	ConstructSigningPayload(unsignedTransaction string) (*SigningPayload, error)
+
+	// CombineTx attaches an externally produced signature to an unsignedTx
+	// @unsignedTransaction [string] base64 encoded unsigned transaction
+	// @signature [[]byte] signature of the signing payload
+	// @return
+	//   - signedTx [string] base64 encoded signed transaction
+	//   - error when the signature does not verify against the From address, or when the fees exceed the fee guard
+	CombineTx(unsignedTransaction string, signature []byte) (string, error)
+
+	// SignTx signs an unsignedTx using the secret key and return a signedTx that can be submitted to the node
+	// The signature type (secp256k1 or BLS) follows the protocol of the From address
+	// @unsignedTransaction [string] base64 encoded unsigned transaction
+	// @sk [[]byte] secp256k1 or BLS secret key
+	// @return
+	//   - signedTx [string] base64 encoded signed transaction
 	//   - error when signing a transaction
 	SignTx(unsignedTransaction string, sk []byte) (string, error)
 
 	// ParseTx defines the function to parse a transaction
-	// @tx [string] signed or unsigned transaction base64 encoded
+	// @tx [string] signed or unsigned transaction, base64 encoded (JSON or CBOR) or plain JSON
 	// @return
-	//   - message [string] the parsed transaction (message or unsigned message) represented as a base64 string
+	//   - message [string] the parsed transaction (signed or unsigned message) base64 encoded in the configured format
 	//   - error when parsing a transaction
 	ParseTx(tx string) (string, error)
 
+	// ParseTxDetailed parses a transaction and decodes the params of the known actor methods (multisig, including the
+	// call of a proposal, init, miner and market) so that what it does can be reviewed
+	// Singleton actors are recognized by their address, the other actors by the params of the method
+	// @tx [string] signed or unsigned transaction, base64 encoded (JSON or CBOR) or plain JSON
+	// @return
+	//   - parsedTx [*ParsedTx] the transaction with its decoded params, unknown params are kept raw
+	//   - error when parsing a transaction
+	ParseTxDetailed(tx string) (*ParsedTx, error)
+
+	// MaxFee computes the maximum fee a transaction can cost, GasFeeCap × GasLimit, whatever the gas actually used
+	// @unsignedTransaction [string] base64 encoded unsigned transaction
+	// @return
+	//   - maxFee [abi.TokenAmount] the maximum fee in attoFIL
+	//   - error when decoding the transaction
+	MaxFee(unsignedTransaction string) (abi.TokenAmount, error)
+
+	// SummarizeTx describes a transaction in clear text to review it before signing, e.g.
+	// "Propose on multisig f0123: send 12.5 FIL to f1abc..., max fee 0.003 FIL, nonce 42"
+	// The summary also has the action and fields of the text, to render it with translated SummaryTemplates
+	// @unsignedTransaction [string] base64 encoded unsigned transaction
+	// @return
+	//   - summary [*TxSummary] the summary of the transaction
+	//   - error when decoding the transaction
+	SummarizeTx(unsignedTransaction string) (*TxSummary, error)
+
 	// Hash defines the function to calculate a tx hash
-	// @signedTx [string] base64 encoded signed transaction
+	// @signedTx [string] signed transaction, base64 encoded (JSON or CBOR) or plain JSON
 	// @return
 	//   - txHash [string] transaction hash
 	//   - error when calculating the tx hash
 	Hash(signedTx string) (string, error)
 }
 
+// SigningPayload defines the data to be signed for a transaction, as returned by ConstructSigningPayload
+type SigningPayload struct {
+	Bytes         []byte         `json:"bytes"`
+	SignatureType crypto.SigType `json:"signature_type"`
+}
+
 // Modify this as needed to add in new fields
+// Amounts (here and in the requests) are big integers in attoFIL, encoded as strings in JSON like Lotus does
 type TxMetadata struct {
-	Nonce      uint64 `json:"nonce"`
-	GasFeeCap  int64  `json:"gas_fee_cap"`
-	GasPremium int64  `json:"gas_premium"`
-	GasLimit   int64  `json:"gas_limit,omitempty"`
-	ChainID    string `json:"chain_id,omitempty"`
-	Method     uint64 `json:"method,omitempty"`
-	Params     []byte `json:"params,omitempty"`
+	Nonce      uint64          `json:"nonce"`
+	GasFeeCap  abi.TokenAmount `json:"gas_fee_cap"`
+	GasPremium abi.TokenAmount `json:"gas_premium"`
+	GasLimit   int64           `json:"gas_limit,omitempty"`
+	ChainID    string          `json:"chain_id,omitempty"`
+	// Method and Params are ignored by the constructors, ConstructMethodCall calls any method
+	Method uint64 `json:"method,omitempty"`
+	Params []byte `json:"params,omitempty"`
 }
 
 // PaymentRequest defines the input to ConstructPayment
 type PaymentRequest struct {
-	From     string     `json:"from"`
-	To       string     `json:"to"`
-	Quantity uint64     `json:"quantity"`
-	Metadata TxMetadata `json:"metadata"`
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Quantity abi.TokenAmount `json:"quantity"`
+	Metadata TxMetadata      `json:"metadata"`
 }
 
 // MultisigPaymentParams defines params for MultisigPaymentRequest
 type MultisigPaymentParams struct {
-	To       string `json:"to"`
-	Quantity uint64 `json:"quantity"`
+	To       string          `json:"to"`
+	Quantity abi.TokenAmount `json:"quantity"`
 }
 
 // MultisigPaymentRequest defines the input to ConstructMultisigPayment
 type MultisigPaymentRequest struct {
-	Multisig string                `json:"multisig"`
-	From     string                `json:"from"`
-	Quantity uint64                `json:"quantity"`
+	Multisig string `json:"multisig"`
+	From     string `json:"from"`
+	// Quantity is optional, when set it must match Params.Quantity: the message itself carries no value
+	Quantity abi.TokenAmount       `json:"quantity"`
 	Metadata TxMetadata            `json:"metadata"`
 	Params   MultisigPaymentParams `json:"params"`
 }
@@ -106,3 +370,301 @@ type SwapAuthorizedPartyRequest struct {
 	Metadata TxMetadata                `json:"metadata"`
 	Params   SwapAuthorizedPartyParams `json:"params"`
 }
+
+// AddSignerParams defines params for AddSignerRequest
+type AddSignerParams struct {
+	Signer string `json:"signer"`
+	// Increase raises the approval threshold by one
+	Increase bool `json:"increase,omitempty"`
+}
+
+// AddSignerRequest defines the input to ConstructAddSigner
+type AddSignerRequest struct {
+	Multisig string          `json:"multisig"`
+	From     string          `json:"from"`
+	Metadata TxMetadata      `json:"metadata"`
+	Params   AddSignerParams `json:"params"`
+}
+
+// RemoveSignerParams defines params for RemoveSignerRequest
+type RemoveSignerParams struct {
+	Signer string `json:"signer"`
+	// Decrease lowers the approval threshold by one
+	Decrease bool `json:"decrease,omitempty"`
+}
+
+// RemoveSignerRequest defines the input to ConstructRemoveSigner
+type RemoveSignerRequest struct {
+	Multisig string             `json:"multisig"`
+	From     string             `json:"from"`
+	Metadata TxMetadata         `json:"metadata"`
+	Params   RemoveSignerParams `json:"params"`
+}
+
+// ChangeNumApprovalsThresholdParams defines params for ChangeNumApprovalsThresholdRequest
+type ChangeNumApprovalsThresholdParams struct {
+	NewThreshold uint64 `json:"new_threshold"`
+}
+
+// ChangeNumApprovalsThresholdRequest defines the input to ConstructChangeNumApprovalsThreshold
+type ChangeNumApprovalsThresholdRequest struct {
+	Multisig string                            `json:"multisig"`
+	From     string                            `json:"from"`
+	Metadata TxMetadata                        `json:"metadata"`
+	Params   ChangeNumApprovalsThresholdParams `json:"params"`
+}
+
+// LockBalanceParams defines params for LockBalanceRequest
+type LockBalanceParams struct {
+	StartEpoch int64 `json:"start_epoch"`
+	// UnlockDuration is the number of epochs over which Amount unlocks, it must be positive
+	UnlockDuration int64           `json:"unlock_duration"`
+	Amount         abi.TokenAmount `json:"amount"`
+}
+
+// LockBalanceRequest defines the input to ConstructLockBalance
+type LockBalanceRequest struct {
+	Multisig string            `json:"multisig"`
+	From     string            `json:"from"`
+	Metadata TxMetadata        `json:"metadata"`
+	Params   LockBalanceParams `json:"params"`
+}
+
+// MultisigCreateParams defines params for MultisigCreateRequest
+type MultisigCreateParams struct {
+	Signers   []string `json:"signers"`
+	Threshold uint64   `json:"threshold"`
+	// UnlockDuration is the number of epochs over which the initial balance vests, zero for no vesting
+	UnlockDuration int64 `json:"unlock_duration,omitempty"`
+	// StartEpoch must be zero: the multisig actor of this network version starts vesting at its creation epoch
+	StartEpoch int64 `json:"start_epoch,omitempty"`
+}
+
+// MultisigCreateRequest defines the input to ConstructMultisigCreate
+type MultisigCreateRequest struct {
+	From string `json:"from"`
+	// Quantity is the initial balance of the multisig
+	Quantity abi.TokenAmount      `json:"quantity"`
+	Metadata TxMetadata           `json:"metadata"`
+	Params   MultisigCreateParams `json:"params"`
+}
+
+// TxnIDParams defines params for MultisigApproveRequest and MultisigCancelRequest
+type TxnIDParams struct {
+	// TxnID is the ID of the pending transaction, as returned by the proposal
+	TxnID int64 `json:"txn_id"`
+	// ProposalHash is optional, when set the call fails unless the pending transaction matches it
+	ProposalHash []byte `json:"proposal_hash,omitempty"`
+}
+
+// MultisigApproveRequest defines the input to ConstructMultisigApprove
+type MultisigApproveRequest struct {
+	Multisig string      `json:"multisig"`
+	From     string      `json:"from"`
+	Metadata TxMetadata  `json:"metadata"`
+	Params   TxnIDParams `json:"params"`
+}
+
+// MultisigCancelRequest defines the input to ConstructMultisigCancel
+type MultisigCancelRequest struct {
+	Multisig string      `json:"multisig"`
+	From     string      `json:"from"`
+	Metadata TxMetadata  `json:"metadata"`
+	Params   TxnIDParams `json:"params"`
+}
+
+// MinerWithdrawBalanceParams defines params for MinerWithdrawBalanceRequest
+type MinerWithdrawBalanceParams struct {
+	AmountRequested abi.TokenAmount `json:"amount_requested"`
+}
+
+// MinerWithdrawBalanceRequest defines the input to ConstructMinerWithdrawBalance
+type MinerWithdrawBalanceRequest struct {
+	Miner string `json:"miner"`
+	From  string `json:"from"`
+	// Multisig, when set, is the owner of the miner: the call is proposed by From on the multisig
+	Multisig string                     `json:"multisig,omitempty"`
+	Metadata TxMetadata                 `json:"metadata"`
+	Params   MinerWithdrawBalanceParams `json:"params"`
+}
+
+// MinerChangeWorkerAddressParams defines params for MinerChangeWorkerAddressRequest
+type MinerChangeWorkerAddressParams struct {
+	NewWorker string `json:"new_worker"`
+	// NewControlAddrs replace the control addresses, an empty list removes them
+	NewControlAddrs []string `json:"new_control_addrs"`
+}
+
+// MinerChangeWorkerAddressRequest defines the input to ConstructMinerChangeWorkerAddress
+type MinerChangeWorkerAddressRequest struct {
+	Miner string `json:"miner"`
+	From  string `json:"from"`
+	// Multisig, when set, is the owner of the miner: the call is proposed by From on the multisig
+	Multisig string                         `json:"multisig,omitempty"`
+	Metadata TxMetadata                     `json:"metadata"`
+	Params   MinerChangeWorkerAddressParams `json:"params"`
+}
+
+// MinerChangePeerIDParams defines params for MinerChangePeerIDRequest
+type MinerChangePeerIDParams struct {
+	// NewID is the libp2p peer ID in base58, e.g. 12D3KooW...
+	NewID string `json:"new_id"`
+}
+
+// MinerChangePeerIDRequest defines the input to ConstructMinerChangePeerID
+type MinerChangePeerIDRequest struct {
+	Miner string `json:"miner"`
+	From  string `json:"from"`
+	// Multisig, when set, is the owner of the miner: the call is proposed by From on the multisig
+	Multisig string                  `json:"multisig,omitempty"`
+	Metadata TxMetadata              `json:"metadata"`
+	Params   MinerChangePeerIDParams `json:"params"`
+}
+
+// MarketAddBalanceParams defines params for MarketAddBalanceRequest
+type MarketAddBalanceParams struct {
+	// ProviderOrClient is the escrow account credited, defaults to From
+	ProviderOrClient string `json:"provider_or_client,omitempty"`
+}
+
+// MarketAddBalanceRequest defines the input to ConstructMarketAddBalance
+type MarketAddBalanceRequest struct {
+	From     string                 `json:"from"`
+	Quantity abi.TokenAmount        `json:"quantity"`
+	Metadata TxMetadata             `json:"metadata"`
+	Params   MarketAddBalanceParams `json:"params"`
+}
+
+// MarketWithdrawBalanceParams defines params for MarketWithdrawBalanceRequest
+type MarketWithdrawBalanceParams struct {
+	// ProviderOrClient is the escrow account debited, defaults to From. The funds go to the client or to the
+	// owner of the provider, From must be the client or the owner or worker of the provider.
+	ProviderOrClient string          `json:"provider_or_client,omitempty"`
+	Amount           abi.TokenAmount `json:"amount"`
+}
+
+// MarketWithdrawBalanceRequest defines the input to ConstructMarketWithdrawBalance
+type MarketWithdrawBalanceRequest struct {
+	From     string                      `json:"from"`
+	Metadata TxMetadata                  `json:"metadata"`
+	Params   MarketWithdrawBalanceParams `json:"params"`
+}
+
+// PaychCreateRequest defines the input to ConstructPaychCreate
+type PaychCreateRequest struct {
+	// From is the payer, it sends the creation message
+	From string `json:"from"`
+	// To is the payee
+	To string `json:"to"`
+	// Quantity is the initial balance of the channel
+	Quantity abi.TokenAmount `json:"quantity"`
+	Metadata TxMetadata      `json:"metadata"`
+}
+
+// VoucherRequest defines the input to CreateVoucher
+type VoucherRequest struct {
+	Channel string `json:"channel"`
+	Lane    uint64 `json:"lane"`
+	// Nonce must be greater than the nonce of the last voucher redeemed on the lane
+	Nonce uint64 `json:"nonce"`
+	// Amount is the total amount redeemable on the lane, not an increment
+	Amount abi.TokenAmount `json:"amount"`
+	// TimeLockMin and TimeLockMax bound the epochs at which the voucher can be redeemed, zero for no bound
+	TimeLockMin int64 `json:"time_lock_min,omitempty"`
+	TimeLockMax int64 `json:"time_lock_max,omitempty"`
+	// SecretPreimage is, despite the name of the actor field, the blake2b-256 hash of the secret the payee
+	// must reveal to redeem the voucher
+	SecretPreimage []byte `json:"secret_preimage,omitempty"`
+	// MinSettleHeight delays the settlement of the channel
+	MinSettleHeight int64 `json:"min_settle_height,omitempty"`
+}
+
+// PaychUpdateChannelStateParams defines params for PaychUpdateChannelStateRequest
+type PaychUpdateChannelStateParams struct {
+	// Voucher is a signed voucher, base64url encoded
+	Voucher string `json:"voucher"`
+	// Secret is the secret whose hash is the SecretPreimage of the voucher
+	Secret []byte `json:"secret,omitempty"`
+	Proof  []byte `json:"proof,omitempty"`
+}
+
+// PaychUpdateChannelStateRequest defines the input to ConstructPaychUpdateChannelState, the channel is the one
+// of the voucher
+type PaychUpdateChannelStateRequest struct {
+	From     string                        `json:"from"`
+	Metadata TxMetadata                    `json:"metadata"`
+	Params   PaychUpdateChannelStateParams `json:"params"`
+}
+
+// PaychRequest defines the input to ConstructPaychSettle and ConstructPaychCollect
+type PaychRequest struct {
+	Channel  string     `json:"channel"`
+	From     string     `json:"from"`
+	Metadata TxMetadata `json:"metadata"`
+}
+
+// VerifregAllowanceParams defines params for VerifregAllowanceRequest
+type VerifregAllowanceParams struct {
+	// Address is the verifier or the client
+	Address string `json:"address"`
+	// Allowance is the datacap in bytes, see ParseDataCap for amounts like "1 TiB"
+	Allowance abi.StoragePower `json:"allowance"`
+}
+
+// VerifregAllowanceRequest defines the input to ConstructVerifregAddVerifier and ConstructVerifregAddVerifiedClient
+type VerifregAllowanceRequest struct {
+	From string `json:"from"`
+	// Multisig, when set, is the root key or verifier: the call is proposed by From on the multisig
+	Multisig string                  `json:"multisig,omitempty"`
+	Metadata TxMetadata              `json:"metadata"`
+	Params   VerifregAllowanceParams `json:"params"`
+}
+
+// VerifregRemoveVerifierParams defines params for VerifregRemoveVerifierRequest
+type VerifregRemoveVerifierParams struct {
+	Address string `json:"address"`
+}
+
+// VerifregRemoveVerifierRequest defines the input to ConstructVerifregRemoveVerifier
+type VerifregRemoveVerifierRequest struct {
+	From string `json:"from"`
+	// Multisig, when set, is the root key: the call is proposed by From on the multisig
+	Multisig string                       `json:"multisig,omitempty"`
+	Metadata TxMetadata                   `json:"metadata"`
+	Params   VerifregRemoveVerifierParams `json:"params"`
+}
+
+// MethodCallRequest defines the input to ConstructMethodCall
+type MethodCallRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Quantity is the value sent with the call
+	Quantity abi.TokenAmount `json:"quantity"`
+	Method   uint64          `json:"method"`
+	// Params are the CBOR encoded params, exclusive with JSONParams
+	Params []byte `json:"params,omitempty"`
+	// JSONParams describe the params as the JSON of their specs-actors type, which the library encodes
+	JSONParams json.RawMessage `json:"json_params,omitempty"`
+	// Actor selects the params types for JSONParams (ActorMultisig, ActorMiner...),
+	// it may be omitted when To is a singleton actor (init, market)
+	Actor    string     `json:"actor,omitempty"`
+	Metadata TxMetadata `json:"metadata"`
+}
+
+// MultisigProposalParams defines params for MultisigProposalRequest
+type MultisigProposalParams struct {
+	// TxnID is the ID of the pending transaction, as returned by the proposal
+	TxnID int64 `json:"txn_id"`
+	// Proposal is the Propose transaction (signed or not), as built by ConstructMultisigPayment or a similar constructor
+	Proposal string `json:"proposal"`
+	// Requester is the ID address of the proposer, see ComputeProposalHash
+	Requester string `json:"requester,omitempty"`
+}
+
+// MultisigProposalRequest defines the input to ConstructMultisigApproveProposal and ConstructMultisigCancelProposal,
+// the multisig is the destination of the proposal
+type MultisigProposalRequest struct {
+	From     string                 `json:"from"`
+	Metadata TxMetadata             `json:"metadata"`
+	Params   MultisigProposalParams `json:"params"`
+}
@@ -0,0 +1,31 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib_test
+
+import (
+	"testing"
+
+	rosettaFilecoinLib "github.com/zondax/rosetta-filecoin-lib"
+	"github.com/zondax/rosetta-filecoin-lib/constructiontest"
+)
+
+func TestRosettaConstructionTool(t *testing.T) {
+	constructiontest.Run(t, &rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false})
+}
+
+func TestRosettaConstructionToolCBOR(t *testing.T) {
+	constructiontest.Run(t, &rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false, Encoding: rosettaFilecoinLib.EncodingCBOR})
+}
@@ -22,19 +22,96 @@ import (
 	"github.com/filecoin-project/go-address"
 	c "github.com/filecoin-project/go-crypto"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
 	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
 	"github.com/minio/blake2b-simd"
 	cbg "github.com/whyrusleeping/cbor-gen"
-
-	"encoding/base64"
-	"encoding/json"
 )
 
 type RosettaConstructionFilecoin struct {
+	// Mainnet selects the network (f prefix on mainnet, t prefix otherwise) of the addresses derived, accepted
+	// and output, including in JSON transactions
 	Mainnet bool
+	// Encoding selects the serialization of the returned transactions (JSON by default)
+	Encoding EncodingFormat
+	// FeeGuard, when set, rejects the signing of transactions with excessive fees
+	FeeGuard *FeeGuard
+}
+
+// RosettaConstructionFilecoin must expose its whole API through RosettaConstructionTool
+var _ RosettaConstructionTool = RosettaConstructionFilecoin{}
+
+// maxAttoFIL is the total FIL supply (2 billion FIL) expressed in attoFIL
+var maxAttoFIL = big.Mul(big.NewInt(2_000_000_000), big.NewInt(1_000_000_000_000_000_000))
+
+// validateAmount checks that amount is a valid attoFIL quantity and returns it, an unset amount being zero
+func validateAmount(name string, amount abi.TokenAmount) (abi.TokenAmount, error) {
+	if amount.Nil() {
+		return big.Zero(), nil
+	}
+
+	if amount.Sign() < 0 {
+		return abi.TokenAmount{}, fmt.Errorf("%s cannot be negative: %s", name, amount)
+	}
+
+	if amount.GreaterThan(maxAttoFIL) {
+		return abi.TokenAmount{}, fmt.Errorf("%s exceeds the FIL supply: %s", name, amount)
+	}
+
+	return amount, nil
+}
+
+// validateGas checks the gas amounts of the metadata
+func validateGas(metadata *TxMetadata) (gasfeecap abi.TokenAmount, gaspremium abi.TokenAmount, err error) {
+	gasfeecap, err = validateAmount("gas fee cap", metadata.GasFeeCap)
+	if err != nil {
+		return
+	}
+
+	gaspremium, err = validateAmount("gas premium", metadata.GasPremium)
+	return
+}
+
+func networkPrefix(mainnet bool) string {
+	if mainnet {
+		return address.MainnetPrefix
+	}
+	return address.TestnetPrefix
+}
+
+// FormatAddress encodes addr for mainnet (f prefix) or testnet (t prefix)
+// go-address only encodes for its global network (https://github.com/filecoin-project/go-address/issues/6)
+// but the network prefix is not covered by the checksum, so it can be swapped
+func FormatAddress(addr address.Address, mainnet bool) string {
+	return networkPrefix(mainnet) + addr.String()[1:]
+}
+
+// networkPrefix returns the address prefix of the configured network
+func (r RosettaConstructionFilecoin) networkPrefix() string {
+	return networkPrefix(r.Mainnet)
+}
+
+// formatAddress encodes addr for the configured network
+func (r RosettaConstructionFilecoin) formatAddress(addr address.Address) string {
+	return FormatAddress(addr, r.Mainnet)
+}
+
+// parseAddress decodes addr and rejects addresses that belong to the other network
+func (r RosettaConstructionFilecoin) parseAddress(addr string) (address.Address, error) {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	if addr[:1] != r.networkPrefix() {
+		return address.Undef, fmt.Errorf("address %s does not belong to the configured network (expected prefix '%s')", addr, r.networkPrefix())
+	}
+
+	return a, nil
 }
 
 func signSecp256k1(msg []byte, pk []byte) ([]byte, error) {
@@ -72,45 +149,97 @@ func verifySecp256k1(sig []byte, a address.Address, msg []byte) error {
 	return fmt.Errorf("invalid signature")
 }
 
+// addressFromPublicKey returns the address of a public key, BLS keys being recognized by their length
+func addressFromPublicKey(publicKey []byte) (address.Address, error) {
+	if len(publicKey) == address.BlsPublicKeyBytes {
+		return address.NewBLSAddress(publicKey)
+	}
+
+	return address.NewSecp256k1Address(publicKey)
+}
+
+// signatureType returns the type of signature expected from an address
+func signatureType(addr address.Address) (crypto.SigType, error) {
+	switch addr.Protocol() {
+	case address.SECP256K1:
+		return crypto.SigTypeSecp256k1, nil
+	case address.BLS:
+		return crypto.SigTypeBLS, nil
+	default:
+		return crypto.SigTypeUnknown, fmt.Errorf("address %s is not a key address, cannot infer its signature type", addr)
+	}
+}
+
+func verifySignature(sig *crypto.Signature, a address.Address, msg []byte) error {
+	switch sig.Type {
+	case crypto.SigTypeSecp256k1:
+		return verifySecp256k1(sig.Data, a, msg)
+	case crypto.SigTypeBLS:
+		return verifyBLS(sig.Data, a, msg)
+	default:
+		return fmt.Errorf("unsupported signature type %d", sig.Type)
+	}
+}
+
 func (r RosettaConstructionFilecoin) DeriveFromPublicKey(publicKey []byte) (string, error) {
-	addr, err := address.NewSecp256k1Address(publicKey)
+	addr, err := addressFromPublicKey(publicKey)
 	if err != nil {
 		return "", err
 	}
-	// FIXME: go=address does not allow setting a network
-	// https://github.com/filecoin-project/go-address/issues/6
 
-	return addr.String(), nil
+	return r.formatAddress(addr), nil
 }
 
 func (r RosettaConstructionFilecoin) Sign(message []byte, sk []byte) ([]byte, error) {
-	return signSecp256k1(message, sk)
+	return r.SignWithType(message, sk, crypto.SigTypeSecp256k1)
+}
+
+func (r RosettaConstructionFilecoin) SignWithType(message []byte, sk []byte, sigType crypto.SigType) ([]byte, error) {
+	switch sigType {
+	case crypto.SigTypeSecp256k1:
+		return signSecp256k1(message, sk)
+	case crypto.SigTypeBLS:
+		return signBLS(message, sk)
+	default:
+		return nil, fmt.Errorf("unsupported signature type %d", sigType)
+	}
 }
 
 func (r RosettaConstructionFilecoin) Verify(message []byte, publicKey []byte, signature []byte) error {
-	addr, err := address.NewSecp256k1Address(publicKey)
+	addr, err := addressFromPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	sigType, err := signatureType(addr)
 	if err != nil {
 		return err
 	}
 
-	return verifySecp256k1(signature, addr, message)
+	return verifySignature(&crypto.Signature{Type: sigType, Data: signature}, addr, message)
 }
 
 func (r RosettaConstructionFilecoin) ConstructPayment(request *PaymentRequest) (string, error) {
-	to, err := address.NewFromString(request.To)
+	to, err := r.parseAddress(request.To)
 	if err != nil {
 		return "", err
 	}
 
-	from, err := address.NewFromString(request.From)
+	from, err := r.parseAddress(request.From)
 	if err != nil {
 		return "", err
 	}
 
-	value := types.NewInt(request.Quantity)
-	gasfeecap := abi.NewTokenAmount(request.Metadata.GasFeeCap)
-	gaspremium := abi.NewTokenAmount(request.Metadata.GasPremium)
-	gaslimit := int64(request.Metadata.GasLimit)
+	value, err := validateAmount("quantity", request.Quantity)
+	if err != nil {
+		return "", err
+	}
+
+	gasfeecap, gaspremium, err := validateGas(&request.Metadata)
+	if err != nil {
+		return "", err
+	}
+	gaslimit := request.Metadata.GasLimit
 
 	msg := &types.Message{Version: types.MessageVersion,
 		To:         to,
@@ -124,36 +253,46 @@ func (r RosettaConstructionFilecoin) ConstructPayment(request *PaymentRequest) (
 		Params:     make([]byte, 0),
 	}
 
-	tx, err := json.Marshal(msg)
+	return r.encodeMessage(msg)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigPayment(request *MultisigPaymentRequest) (string, error) {
+	to, err := r.parseAddress(request.Multisig)
 	if err != nil {
 		return "", err
 	}
 
-	return base64.StdEncoding.EncodeToString(tx), nil
-}
-
-func (r RosettaConstructionFilecoin) ConstructMultisigPayment(request *MultisigPaymentRequest) (string, error) {
-	to, err := address.NewFromString(request.Multisig)
+	from, err := r.parseAddress(request.From)
 	if err != nil {
 		return "", err
 	}
 
-	from, err := address.NewFromString(request.From)
+	quantity, err := validateAmount("quantity", request.Quantity)
 	if err != nil {
 		return "", err
 	}
 
 	value := types.NewInt(0)
-	gasfeecap := abi.NewTokenAmount(request.Metadata.GasFeeCap)
-	gaspremium := abi.NewTokenAmount(request.Metadata.GasPremium)
-	gaslimit := int64(request.Metadata.GasLimit)
+	gasfeecap, gaspremium, err := validateGas(&request.Metadata)
+	if err != nil {
+		return "", err
+	}
+	gaslimit := request.Metadata.GasLimit
+
+	toParams, err := r.parseAddress(request.Params.To)
+	if err != nil {
+		return "", err
+	}
 
-	toParams, err := address.NewFromString(request.Params.To)
+	valueParams, err := validateAmount("params quantity", request.Params.Quantity)
 	if err != nil {
 		return "", err
 	}
 
-	valueParams := types.NewInt(request.Params.Quantity)
+	// The multisig pays the proposed value, the message itself carries none
+	if !quantity.IsZero() && !quantity.Equals(valueParams) {
+		return "", fmt.Errorf("quantity %s does not match the proposed value %s", quantity, valueParams)
+	}
 
 	params := &multisig.ProposeParams{
 		To:     toParams,
@@ -182,36 +321,95 @@ func (r RosettaConstructionFilecoin) ConstructMultisigPayment(request *MultisigP
 		Params:     serParams,
 	}
 
-	tx, err := json.Marshal(msg)
+	return r.encodeMessage(msg)
+}
+
+// constructSelfProposal creates a Propose call of a multisig targeting itself, used for signer management
+func (r RosettaConstructionFilecoin) constructSelfProposal(multisigAddr string, fromAddr string, metadata *TxMetadata,
+	method abi.MethodNum, methodParams cbg.CBORMarshaler) (string, error) {
+	return r.constructActorCall(multisigAddr, fromAddr, multisigAddr, big.Zero(), metadata, method, methodParams)
+}
+
+// constructActorCall creates a call of method of the actor at toAddr carrying value, sent by fromAddr or, when
+// multisigAddr is set, proposed by fromAddr on the multisig which then pays the value. methodParams is nil for a
+// method without params.
+func (r RosettaConstructionFilecoin) constructActorCall(toAddr string, fromAddr string, multisigAddr string,
+	value abi.TokenAmount, metadata *TxMetadata, method abi.MethodNum, methodParams cbg.CBORMarshaler) (string, error) {
+	to, err := r.parseAddress(toAddr)
 	if err != nil {
 		return "", err
 	}
 
-	return base64.StdEncoding.EncodeToString(tx), nil
-}
+	from, err := r.parseAddress(fromAddr)
+	if err != nil {
+		return "", err
+	}
 
-func (r RosettaConstructionFilecoin) ConstructSwapAuthorizedParty(request *SwapAuthorizedPartyRequest) (string, error) {
-	to, err := address.NewFromString(request.Multisig)
+	gasfeecap, gaspremium, err := validateGas(metadata)
 	if err != nil {
 		return "", err
 	}
+	gaslimit := metadata.GasLimit
+
+	var serMethodParams []byte
+	if methodParams != nil {
+		bufMethod := new(bytes.Buffer)
+		err = methodParams.MarshalCBOR(bufMethod)
+		if err != nil {
+			return "", err
+		}
+		serMethodParams = bufMethod.Bytes()
+	}
 
-	from, err := address.NewFromString(request.From)
+	msg := &types.Message{Version: types.MessageVersion,
+		To:         to,
+		From:       from,
+		Nonce:      metadata.Nonce,
+		Value:      value,
+		GasFeeCap:  gasfeecap,
+		GasPremium: gaspremium,
+		GasLimit:   gaslimit,
+		Method:     method,
+		Params:     serMethodParams,
+	}
+
+	if multisigAddr == "" {
+		return r.encodeMessage(msg)
+	}
+
+	msig, err := r.parseAddress(multisigAddr)
 	if err != nil {
 		return "", err
 	}
 
-	value := types.NewInt(0)
-	gasfeecap := abi.NewTokenAmount(request.Metadata.GasFeeCap)
-	gaspremium := abi.NewTokenAmount(request.Metadata.GasPremium)
-	gaslimit := int64(request.Metadata.GasLimit)
+	params := &multisig.ProposeParams{
+		To:     to,
+		Value:  value,
+		Method: method,
+		Params: serMethodParams,
+	}
 
-	toParams, err := address.NewFromString(request.Params.To)
+	buf := new(bytes.Buffer)
+	err = params.MarshalCBOR(buf)
+	if err != nil {
+		return "", err
+	}
+
+	msg.To = msig
+	msg.Value = types.NewInt(0)
+	msg.Method = builtin.MethodsMultisig.Propose
+	msg.Params = buf.Bytes()
+
+	return r.encodeMessage(msg)
+}
+
+func (r RosettaConstructionFilecoin) ConstructSwapAuthorizedParty(request *SwapAuthorizedPartyRequest) (string, error) {
+	toParams, err := r.parseAddress(request.Params.To)
 	if err != nil {
 		return "", err
 	}
 
-	fromParams, err := address.NewFromString(request.Params.From)
+	fromParams, err := r.parseAddress(request.Params.From)
 	if err != nil {
 		return "", err
 	}
@@ -221,18 +419,137 @@ func (r RosettaConstructionFilecoin) ConstructSwapAuthorizedParty(request *SwapA
 		To:   toParams,
 	}
 
-	bufSwapSigner := new(bytes.Buffer)
-	err = swapSignerParams.MarshalCBOR(bufSwapSigner)
+	return r.constructSelfProposal(request.Multisig, request.From, &request.Metadata,
+		builtin.MethodsMultisig.SwapSigner, swapSignerParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructAddSigner(request *AddSignerRequest) (string, error) {
+	signer, err := r.parseAddress(request.Params.Signer)
 	if err != nil {
 		return "", err
 	}
-	serSwapSignersParams := bufSwapSigner.Bytes()
 
-	params := &multisig.ProposeParams{
-		To:     to,
-		Value:  value,
-		Method: builtin.MethodsMultisig.SwapSigner,
-		Params: serSwapSignersParams,
+	addSignerParams := &multisig.AddSignerParams{
+		Signer:   signer,
+		Increase: request.Params.Increase,
+	}
+
+	return r.constructSelfProposal(request.Multisig, request.From, &request.Metadata,
+		builtin.MethodsMultisig.AddSigner, addSignerParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructRemoveSigner(request *RemoveSignerRequest) (string, error) {
+	signer, err := r.parseAddress(request.Params.Signer)
+	if err != nil {
+		return "", err
+	}
+
+	removeSignerParams := &multisig.RemoveSignerParams{
+		Signer:   signer,
+		Decrease: request.Params.Decrease,
+	}
+
+	return r.constructSelfProposal(request.Multisig, request.From, &request.Metadata,
+		builtin.MethodsMultisig.RemoveSigner, removeSignerParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructChangeNumApprovalsThreshold(request *ChangeNumApprovalsThresholdRequest) (string, error) {
+	if request.Params.NewThreshold == 0 {
+		return "", fmt.Errorf("threshold must be at least 1")
+	}
+
+	thresholdParams := &multisig.ChangeNumApprovalsThresholdParams{
+		NewThreshold: request.Params.NewThreshold,
+	}
+
+	return r.constructSelfProposal(request.Multisig, request.From, &request.Metadata,
+		builtin.MethodsMultisig.ChangeNumApprovalsThreshold, thresholdParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructLockBalance(request *LockBalanceRequest) (string, error) {
+	if request.Params.UnlockDuration <= 0 {
+		return "", fmt.Errorf("unlock duration must be positive")
+	}
+
+	amount, err := validateAmount("amount", request.Params.Amount)
+	if err != nil {
+		return "", err
+	}
+
+	lockBalanceParams := &multisig.LockBalanceParams{
+		StartEpoch:     abi.ChainEpoch(request.Params.StartEpoch),
+		UnlockDuration: abi.ChainEpoch(request.Params.UnlockDuration),
+		Amount:         amount,
+	}
+
+	return r.constructSelfProposal(request.Multisig, request.From, &request.Metadata,
+		builtin.MethodsMultisig.LockBalance, lockBalanceParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigCreate(request *MultisigCreateRequest) (string, error) {
+	from, err := r.parseAddress(request.From)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := validateAmount("quantity", request.Quantity)
+	if err != nil {
+		return "", err
+	}
+
+	gasfeecap, gaspremium, err := validateGas(&request.Metadata)
+	if err != nil {
+		return "", err
+	}
+	gaslimit := request.Metadata.GasLimit
+
+	if len(request.Params.Signers) == 0 {
+		return "", fmt.Errorf("a multisig needs at least one signer")
+	}
+
+	signers := make([]address.Address, 0, len(request.Params.Signers))
+	seen := make(map[address.Address]bool, len(request.Params.Signers))
+	for _, s := range request.Params.Signers {
+		signer, err := r.parseAddress(s)
+		if err != nil {
+			return "", err
+		}
+
+		if seen[signer] {
+			return "", fmt.Errorf("duplicate signer %s", s)
+		}
+		seen[signer] = true
+		signers = append(signers, signer)
+	}
+
+	if request.Params.Threshold == 0 || request.Params.Threshold > uint64(len(signers)) {
+		return "", fmt.Errorf("threshold must be between 1 and the number of signers (%d)", len(signers))
+	}
+
+	if request.Params.UnlockDuration < 0 {
+		return "", fmt.Errorf("unlock duration cannot be negative")
+	}
+
+	// specs-actors v0.9 multisigs have no start epoch parameter, vesting starts when the multisig is created
+	if request.Params.StartEpoch != 0 {
+		return "", fmt.Errorf("start epoch is not supported by the multisig actor of this network version")
+	}
+
+	constructorParams := &multisig.ConstructorParams{
+		Signers:               signers,
+		NumApprovalsThreshold: request.Params.Threshold,
+		UnlockDuration:        abi.ChainEpoch(request.Params.UnlockDuration),
+	}
+
+	bufConstructor := new(bytes.Buffer)
+	err = constructorParams.MarshalCBOR(bufConstructor)
+	if err != nil {
+		return "", err
+	}
+
+	params := &init_.ExecParams{
+		CodeCID:           builtin.MultisigActorCodeID,
+		ConstructorParams: bufConstructor.Bytes(),
 	}
 
 	buf := new(bytes.Buffer)
@@ -243,126 +560,253 @@ func (r RosettaConstructionFilecoin) ConstructSwapAuthorizedParty(request *SwapA
 	serParams := buf.Bytes()
 
 	msg := &types.Message{Version: types.MessageVersion,
-		To:         to,
+		To:         builtin.InitActorAddr,
 		From:       from,
 		Nonce:      request.Metadata.Nonce,
 		Value:      value,
 		GasFeeCap:  gasfeecap,
 		GasPremium: gaspremium,
 		GasLimit:   gaslimit,
-		Method:     builtin.MethodsMultisig.Propose,
+		Method:     builtin.MethodsInit.Exec,
 		Params:     serParams,
 	}
 
-	tx, err := json.Marshal(msg)
+	return r.encodeMessage(msg)
+}
+
+// proposalHashBytes is the size of a proposal hash (blake2b-256)
+const proposalHashBytes = 32
+
+// constructTxnIDMessage creates a multisig call identifying a pending transaction (Approve or Cancel)
+func (r RosettaConstructionFilecoin) constructTxnIDMessage(method abi.MethodNum, multisigAddr string, fromAddr string,
+	metadata *TxMetadata, txnParams *TxnIDParams) (string, error) {
+	to, err := r.parseAddress(multisigAddr)
 	if err != nil {
 		return "", err
 	}
 
-	return base64.StdEncoding.EncodeToString(tx), nil
-}
+	from, err := r.parseAddress(fromAddr)
+	if err != nil {
+		return "", err
+	}
 
-func (r RosettaConstructionFilecoin) SignTx(unsignedTxBase64 string, privateKey []byte) (string, error) {
-	unsignedTransaction, err := base64.StdEncoding.DecodeString(unsignedTxBase64)
+	value := types.NewInt(0)
+	gasfeecap, gaspremium, err := validateGas(metadata)
 	if err != nil {
 		return "", err
 	}
+	gaslimit := metadata.GasLimit
+
+	if txnParams.TxnID < 0 {
+		return "", fmt.Errorf("invalid transaction ID %d", txnParams.TxnID)
+	}
 
-	rawIn := json.RawMessage(unsignedTransaction)
+	if len(txnParams.ProposalHash) != 0 && len(txnParams.ProposalHash) != proposalHashBytes {
+		return "", fmt.Errorf("proposal hash must be %d bytes long", proposalHashBytes)
+	}
 
-	bytes, err := rawIn.MarshalJSON()
+	params := &multisig.TxnIDParams{
+		ID:           multisig.TxnID(txnParams.TxnID),
+		ProposalHash: txnParams.ProposalHash,
+	}
+
+	buf := new(bytes.Buffer)
+	err = params.MarshalCBOR(buf)
 	if err != nil {
 		return "", err
 	}
+	serParams := buf.Bytes()
+
+	msg := &types.Message{Version: types.MessageVersion,
+		To:         to,
+		From:       from,
+		Nonce:      metadata.Nonce,
+		Value:      value,
+		GasFeeCap:  gasfeecap,
+		GasPremium: gaspremium,
+		GasLimit:   gaslimit,
+		Method:     method,
+		Params:     serParams,
+	}
 
-	var msg types.Message
-	err = json.Unmarshal(bytes, &msg)
+	return r.encodeMessage(msg)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigApprove(request *MultisigApproveRequest) (string, error) {
+	return r.constructTxnIDMessage(builtin.MethodsMultisig.Approve, request.Multisig, request.From, &request.Metadata, &request.Params)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigCancel(request *MultisigCancelRequest) (string, error) {
+	return r.constructTxnIDMessage(builtin.MethodsMultisig.Cancel, request.Multisig, request.From, &request.Metadata, &request.Params)
+}
+
+func (r RosettaConstructionFilecoin) ComputeProposalHash(tx string, requester string) ([]byte, error) {
+	msg, sm, err := DecodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm != nil {
+		msg = &sm.Message
+	}
+
+	return r.proposalHash(msg, requester)
+}
+
+// proposalHash computes the ProposalHashData hash of the transaction proposed by msg
+func (r RosettaConstructionFilecoin) proposalHash(msg *types.Message, requester string) ([]byte, error) {
+	if msg.Method != builtin.MethodsMultisig.Propose || msg.To == builtin.InitActorAddr {
+		return nil, fmt.Errorf("method %d is not a multisig proposal", msg.Method)
+	}
+
+	requesterAddr := msg.From
+	if requester != "" {
+		var err error
+		requesterAddr, err = r.parseAddress(requester)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if requesterAddr.Protocol() != address.ID {
+		return nil, fmt.Errorf("the requester must be the ID address of %s", msg.From)
+	}
+
+	var params multisig.ProposeParams
+	err := params.UnmarshalCBOR(bytes.NewReader(msg.Params))
+	if err != nil {
+		return nil, err
+	}
+
+	txn := &multisig.Transaction{
+		To:       params.To,
+		Value:    params.Value,
+		Method:   params.Method,
+		Params:   params.Params,
+		Approved: []address.Address{requesterAddr},
+	}
+
+	return multisig.ComputeProposalHash(txn, blake2b.Sum256)
+}
+
+// constructProposalTxnIDMessage creates an Approve or Cancel call of the proposal of the request, with its hash
+func (r RosettaConstructionFilecoin) constructProposalTxnIDMessage(method abi.MethodNum, request *MultisigProposalRequest) (string, error) {
+	msg, sm, err := DecodeTx(request.Params.Proposal)
 	if err != nil {
 		return "", err
 	}
 
-	digest := msg.Cid().Bytes()
+	if sm != nil {
+		msg = &sm.Message
+	}
 
-	sig, err := r.Sign(digest, privateKey)
+	hash, err := r.proposalHash(msg, request.Params.Requester)
 	if err != nil {
 		return "", err
 	}
 
-	signature := crypto.Signature{
-		Type: crypto.SigTypeSecp256k1,
-		Data: sig,
+	txnParams := &TxnIDParams{
+		TxnID:        request.Params.TxnID,
+		ProposalHash: hash,
 	}
 
-	sm := &types.SignedMessage{
-		Message:   msg,
-		Signature: signature,
+	return r.constructTxnIDMessage(method, r.formatAddress(msg.To), request.From, &request.Metadata, txnParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigApproveProposal(request *MultisigProposalRequest) (string, error) {
+	return r.constructProposalTxnIDMessage(builtin.MethodsMultisig.Approve, request)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMultisigCancelProposal(request *MultisigProposalRequest) (string, error) {
+	return r.constructProposalTxnIDMessage(builtin.MethodsMultisig.Cancel, request)
+}
+
+func (r RosettaConstructionFilecoin) ConstructSigningPayload(unsignedTx string) (*SigningPayload, error) {
+	msg, err := decodeUnsignedTx(unsignedTx)
+	if err != nil {
+		return nil, err
 	}
 
-	m, err := json.Marshal(sm)
+	err = r.checkFees(msg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(m), nil
+	sigType, err := signatureType(msg.From)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningPayload{
+		Bytes:         msg.Cid().Bytes(),
+		SignatureType: sigType,
+	}, nil
 }
 
-func (r RosettaConstructionFilecoin) ParseTx(messageBase64 string) (string, error) {
-	messageCbor, err := base64.StdEncoding.DecodeString(messageBase64)
+func (r RosettaConstructionFilecoin) CombineTx(unsignedTx string, signature []byte) (string, error) {
+	msg, err := decodeUnsignedTx(unsignedTx)
 	if err != nil {
 		return "", err
 	}
 
-	br := cbg.GetPeeker(bytes.NewReader(messageCbor))
-	scratch := make([]byte, 8)
-	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	err = r.checkFees(msg)
+	if err != nil {
+		return "", err
+	}
 
+	sigType, err := signatureType(msg.From)
 	if err != nil {
 		return "", err
 	}
 
-	if maj != cbg.MajArray {
-		return "", fmt.Errorf("cbor input should be of type array")
+	sm := &types.SignedMessage{
+		Message: *msg,
+		Signature: crypto.Signature{
+			Type: sigType,
+			Data: signature,
+		},
+	}
+
+	err = verifySignature(&sm.Signature, msg.From, msg.Cid().Bytes())
+	if err != nil {
+		return "", fmt.Errorf("signature does not match the sender %s: %v", msg.From, err)
 	}
 
-	var msg interface{}
+	return r.encodeSignedMessage(sm)
+}
 
-	switch extra {
-	case 10:
-		// Unsigned message
-		msg, err = types.DecodeMessage(messageCbor)
-		if err != nil {
-			return "", err
-		}
-	case 2:
-		// Signed message
-		msg, err = types.DecodeSignedMessage(messageCbor)
-		if err != nil {
-			return "", err
-		}
-	default:
-		return "", fmt.Errorf("cbor input had wrong number of fields")
+func (r RosettaConstructionFilecoin) SignTx(unsignedTx string, privateKey []byte) (string, error) {
+	payload, err := r.ConstructSigningPayload(unsignedTx)
+	if err != nil {
+		return "", err
 	}
 
-	msgBytes, err := json.Marshal(msg)
+	sig, err := r.SignWithType(payload.Bytes, privateKey, payload.SignatureType)
 	if err != nil {
 		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(msgBytes), nil
-}
 
-func (r RosettaConstructionFilecoin) Hash(signedMessage string) (string, error) {
-	rawIn := json.RawMessage(signedMessage)
+	return r.CombineTx(unsignedTx, sig)
+}
 
-	bytes, err := rawIn.MarshalJSON()
+func (r RosettaConstructionFilecoin) ParseTx(tx string) (string, error) {
+	msg, sm, err := DecodeTx(tx)
 	if err != nil {
 		return "", err
 	}
 
-	var msg types.SignedMessage
-	err = json.Unmarshal(bytes, &msg)
+	if sm != nil {
+		return r.encodeSignedMessage(sm)
+	}
+
+	return r.encodeMessage(msg)
+}
+
+func (r RosettaConstructionFilecoin) Hash(signedTx string) (string, error) {
+	sm, err := decodeSignedTx(signedTx)
 	if err != nil {
 		return "", err
 	}
 
-	return msg.Cid().String(), nil
+	return sm.Cid().String(), nil
 }
@@ -17,15 +17,24 @@ package rosettaFilecoinLib
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"github.com/filecoin-project/lotus/chain/types"
-	"net/http"
 	"os"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+
+	"github.com/zondax/rosetta-filecoin-lib/lotusclient"
+	"github.com/zondax/rosetta-filecoin-lib/lotustest"
 )
 
 const MULTISIG_ADDRESS = "t020286"
@@ -45,7 +54,7 @@ func TestDeriveFromPublicKey(t *testing.T) {
 		t.Errorf("Invalid test case")
 	}
 
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 
 	address, err := r.DeriveFromPublicKey(pk)
 	if err != nil {
@@ -58,6 +67,77 @@ func TestDeriveFromPublicKey(t *testing.T) {
 
 }
 
+func TestDeriveFromPublicKeyMainnet(t *testing.T) {
+	pk, err := hex.DecodeString("04fc016f3d88dc7070cdd95b5754d32fd5290f850b7c2208fca0f715d35861de1841d9a342a487692a63810a6c906b443a18aa804d9d508d69facc5b06789a01b4")
+	if err != nil {
+		t.Errorf("Invalid test case")
+	}
+
+	r := &RosettaConstructionFilecoin{Mainnet: true}
+
+	address, err := r.DeriveFromPublicKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if address != "f1rovwtiuo5ncslpmpjftzu5akswbgsgighjazxoi" {
+		t.Errorf("unexpected address %s", address)
+	}
+}
+
+func TestConstructRejectsOtherNetwork(t *testing.T) {
+	mtx := TxMetadata{
+		Nonce:      1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
+		GasLimit:   25000,
+	}
+
+	testnet := &RosettaConstructionFilecoin{Mainnet: false}
+	mainnet := &RosettaConstructionFilecoin{Mainnet: true}
+
+	pr := &PaymentRequest{
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		To:       "f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(1),
+		Metadata: mtx,
+	}
+	if _, err := testnet.ConstructPayment(pr); err == nil {
+		t.Error("mainnet destination should be rejected on testnet")
+	}
+
+	pr.From = "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+	if _, err := mainnet.ConstructPayment(pr); err != nil {
+		t.Errorf("mainnet payment should be accepted on mainnet: %v", err)
+	}
+
+	msig := &MultisigPaymentRequest{
+		Multisig: "t01002",
+		From:     "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		Metadata: mtx,
+		Params: MultisigPaymentParams{
+			To:       "f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+			Quantity: abi.NewTokenAmount(1),
+		},
+	}
+	if _, err := mainnet.ConstructMultisigPayment(msig); err == nil {
+		t.Error("testnet multisig should be rejected on mainnet")
+	}
+
+	swap := &SwapAuthorizedPartyRequest{
+		Multisig: "f01002",
+		From:     "f137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy",
+		Metadata: mtx,
+		Params: SwapAuthorizedPartyParams{
+			From: "f137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy",
+			To:   "t14q6mgxil4ism6a6vp2ee375wfjyionl46wtle5q",
+		},
+	}
+	if _, err := mainnet.ConstructSwapAuthorizedParty(swap); err == nil {
+		t.Error("testnet signer should be rejected on mainnet")
+	}
+}
+
 func TestSign(t *testing.T) {
 	unsignedTx := `{
     "To": "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
@@ -74,7 +154,7 @@ func TestSign(t *testing.T) {
 	if err != nil {
 		t.Errorf("FIX ME")
 	}
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 
 	rawIn := json.RawMessage(unsignedTx)
 
@@ -123,7 +203,7 @@ func TestVerify(t *testing.T) {
 	if err != nil {
 		t.Errorf("FIX ME")
 	}
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 
 	rawIn := json.RawMessage(unsignedTx)
 
@@ -150,17 +230,17 @@ func TestVerify(t *testing.T) {
 
 func TestConstructPayment(t *testing.T) {
 	expected := `{"Version":0,"To":"t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","Nonce":1,"Value":"100000","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":0,"Params":""}`
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	mtx := TxMetadata{
 		Nonce:      1,
-		GasFeeCap:  1,
-		GasPremium: 1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
 		GasLimit:   25000,
 	}
 	pr := &PaymentRequest{
 		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
 		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
-		Quantity: 100000,
+		Quantity: abi.NewTokenAmount(100000),
 		Metadata: mtx,
 	}
 
@@ -175,18 +255,75 @@ func TestConstructPayment(t *testing.T) {
 
 }
 
+func TestConstructPaymentBigAmount(t *testing.T) {
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+
+	// 5000 FIL does not fit in an uint64 of attoFIL
+	quantity, err := big.FromString("5000000000000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := `{"from":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","to":"t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy","quantity":"5000000000000000000000","metadata":{"nonce":1,"gas_fee_cap":"1","gas_premium":"1","gas_limit":25000}}`
+	var pr PaymentRequest
+	err = json.Unmarshal([]byte(request), &pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pr.Quantity.Equals(quantity) {
+		t.Fatalf("quantity not decoded: %s", pr.Quantity)
+	}
+
+	txBase64, err := r.ConstructPayment(&pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := base64.StdEncoding.DecodeString(txBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg types.Message
+	err = json.Unmarshal(tx, &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !msg.Value.Equals(quantity) {
+		t.Errorf("unexpected value %s", msg.Value)
+	}
+
+	pr.Quantity = abi.NewTokenAmount(-1)
+	if _, err := r.ConstructPayment(&pr); err == nil {
+		t.Error("negative quantity should fail")
+	}
+
+	pr.Quantity = big.Add(maxAttoFIL, big.NewInt(1))
+	if _, err := r.ConstructPayment(&pr); err == nil {
+		t.Error("quantity above FIL supply should fail")
+	}
+
+	pr.Quantity = quantity
+	pr.Metadata.GasPremium = abi.NewTokenAmount(-1)
+	if _, err := r.ConstructPayment(&pr); err == nil {
+		t.Error("negative gas premium should fail")
+	}
+}
+
 func TestConstructMultisigPayment(t *testing.T) {
 	expected := `{"Version":0,"To":"t01002","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","Nonce":1,"Value":"0","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":2,"Params":"hFUB/R0PTfzX6Zr8uZqDJrfcRZ0yxihDAAPoAEA="}`
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	mtx := TxMetadata{
 		Nonce:      1,
-		GasFeeCap:  1,
-		GasPremium: 1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
 		GasLimit:   25000,
 	}
 	params := MultisigPaymentParams{
 		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
-		Quantity: 1000,
+		Quantity: abi.NewTokenAmount(1000),
 	}
 	request := &MultisigPaymentRequest{
 		Multisig: "t01002",
@@ -203,15 +340,78 @@ func TestConstructMultisigPayment(t *testing.T) {
 	if txBase64 != base64.StdEncoding.EncodeToString([]byte(expected)) {
 		t.Fail()
 	}
+
+	// A top-level quantity is only accepted when it is the proposed value
+	request.Quantity = abi.NewTokenAmount(1000)
+	if tx, err := r.ConstructMultisigPayment(request); err != nil || tx != txBase64 {
+		t.Errorf("matching quantity should be accepted: %v", err)
+	}
+
+	request.Quantity = abi.NewTokenAmount(500)
+	if _, err := r.ConstructMultisigPayment(request); err == nil {
+		t.Error("quantity different from the proposed value should be rejected")
+	}
+}
+
+func TestConstructMultisigApprove(t *testing.T) {
+	expected := `{"Version":0,"To":"t01002","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","Nonce":1,"Value":"0","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":3,"Params":"ggNA"}`
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	mtx := TxMetadata{
+		Nonce:      1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
+		GasLimit:   25000,
+	}
+	request := &MultisigApproveRequest{
+		Multisig: "t01002",
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		Metadata: mtx,
+		Params:   TxnIDParams{TxnID: 3},
+	}
+
+	txBase64, err := r.ConstructMultisigApprove(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txBase64 != base64.StdEncoding.EncodeToString([]byte(expected)) {
+		t.Fail()
+	}
+}
+
+func TestConstructMultisigCancel(t *testing.T) {
+	expected := `{"Version":0,"To":"t01002","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","Nonce":1,"Value":"0","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":4,"Params":"ggNA"}`
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	mtx := TxMetadata{
+		Nonce:      1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
+		GasLimit:   25000,
+	}
+	request := &MultisigCancelRequest{
+		Multisig: "t01002",
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		Metadata: mtx,
+		Params:   TxnIDParams{TxnID: 3},
+	}
+
+	txBase64, err := r.ConstructMultisigCancel(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txBase64 != base64.StdEncoding.EncodeToString([]byte(expected)) {
+		t.Fail()
+	}
 }
 
 func TestConstructSwapAuthorizedParty(t *testing.T) {
 	expected := `{"Version":0,"To":"t01002","From":"t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy","Nonce":1,"Value":"0","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":2,"Params":"hEMA6gdAB1gtglUB3+SRhNRq3I+J1EY4vrRfePytJZBVAeQ8w10L4iTPA9V+iE3/tipwhzV8"}`
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	mtx := TxMetadata{
 		Nonce:      1,
-		GasFeeCap:  1,
-		GasPremium: 1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
 		GasLimit:   25000,
 	}
 	params := SwapAuthorizedPartyParams{
@@ -239,7 +439,7 @@ func TestConstructSwapAuthorizedParty(t *testing.T) {
 func TestSignTx(t *testing.T) {
 	unsignedTxBase64 := "eyJWZXJzaW9uIjowLCJUbyI6InQxN3VvcTZ0cDQyN3V6djdmenRrYnNubjY0aXdvdGZycmlzdHdwcnl5IiwiRnJvbSI6InQxZDJ4cnpjc2x4N3hsYmJ5bGM1YzNkNWx2YW5kcXc0aXdsNmVweGJhIiwiTm9uY2UiOjEsIlZhbHVlIjoiMTAwMDAwIiwiR2FzRmVlQ2FwIjoiMSIsIkdhc1ByZW1pdW0iOiIxIiwiR2FzTGltaXQiOjI1MDAwLCJNZXRob2QiOjAsIlBhcmFtcyI6IiJ9"
 	sk := "f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a"
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 
 	skBytes, err := hex.DecodeString(sk)
 
@@ -254,11 +454,9 @@ func TestSignTx(t *testing.T) {
 
 	t.Log(signedTx)
 
-	rawIn := json.RawMessage(signedTx)
-
-	bytes, err := rawIn.MarshalJSON()
+	bytes, err := base64.StdEncoding.DecodeString(signedTx)
 	if err != nil {
-		t.Errorf("Not a json string")
+		t.Errorf("Not a base64 string")
 	}
 
 	var msg types.SignedMessage
@@ -274,11 +472,57 @@ func TestSignTx(t *testing.T) {
 
 }
 
+func TestSigningPayloadAndCombine(t *testing.T) {
+	unsignedTxBase64 := "eyJWZXJzaW9uIjowLCJUbyI6InQxN3VvcTZ0cDQyN3V6djdmenRrYnNubjY0aXdvdGZycmlzdHdwcnl5IiwiRnJvbSI6InQxZDJ4cnpjc2x4N3hsYmJ5bGM1YzNkNWx2YW5kcXc0aXdsNmVweGJhIiwiTm9uY2UiOjEsIlZhbHVlIjoiMTAwMDAwIiwiR2FzRmVlQ2FwIjoiMSIsIkdhc1ByZW1pdW0iOiIxIiwiR2FzTGltaXQiOjI1MDAwLCJNZXRob2QiOjAsIlBhcmFtcyI6IiJ9"
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+
+	payload, err := r.ConstructSigningPayload(unsignedTxBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.SignatureType != crypto.SigTypeSecp256k1 {
+		t.Errorf("unexpected signature type %d", payload.SignatureType)
+	}
+
+	// External signer
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	sig, err := r.Sign(payload.Bytes, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedTx, err := r.CombineTx(unsignedTxBase64, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := r.SignTx(unsignedTxBase64, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signedTx != expected {
+		t.Errorf("combined transaction differs from SignTx output")
+	}
+
+	// Signed by another key
+	otherSk, _ := hex.DecodeString("61b0cf875beaddf0429736e2c03b7a5a39e201d667f2d35c0b07013b6843c329")
+	otherSig, err := r.Sign(payload.Bytes, otherSk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.CombineTx(unsignedTxBase64, otherSig); err == nil {
+		t.Error("signature of another key should be rejected")
+	}
+}
+
 func TestParseTx(t *testing.T) {
 	expected := `{"Version":0,"To":"t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy","From":"t1xcbgdhkgkwht3hrrnui3jdopeejsoas2rujnkdi","Nonce":1,"Value":"100000","GasLimit":25000,"GasFeeCap":"1","GasPremium":"1","Method":0,"Params":null}`
 	serializedTx := "8A005501FD1D0F4DFCD7E99AFCB99A8326B7DC459D32C6285501B882619D46558F3D9E316D11B48DCF211327025A0144000186A01961A84200014200010040"
 
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	b, err := hex.DecodeString(serializedTx)
 
 	msgBase64 := base64.StdEncoding.EncodeToString(b)
@@ -319,7 +563,7 @@ func TestHash(t *testing.T) {
       "Data": "0wRrFJZFIVh8m0JD+f5C55YrxD6YAWtCXWYihrPTKdMfgMhYAy86MVhs43hSLXnV+47UReRIe8qFdHRJqFlreAE="
     }
   }`
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 
 	cid, err := r.Hash(signedTx)
 
@@ -336,164 +580,127 @@ func TestHash(t *testing.T) {
 
 /*  On Chain Tests */
 
-// send from regular address
-func TestSendTransaction(t *testing.T) {
-	defer seq()()
+// lotusClient returns a client of the node at LOTUS_URL, or of a fake node holding
+// the accounts and multisig of the devnet when LOTUS_URL is unset
+func lotusClient(t *testing.T) *lotusclient.Client {
+	if url := os.Getenv("LOTUS_URL"); url != "" {
+		return lotusclient.NewClient(url, os.Getenv("LOTUS_JWT"))
+	}
 
-	/* Secret Key */
-	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	node := lotustest.NewNode(func(sm *types.SignedMessage) error {
+		return verifySignature(&sm.Signature, sm.Message.From, sm.Message.Cid().Bytes())
+	})
+	t.Cleanup(node.Close)
 
-	/* Get Nonce */
-	data := []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolGetNonce","id": 1, "params": ["t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"]}`)
+	sender := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	signer1 := mustAddress(t, "t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy")
+	signer2 := mustAddress(t, "t1itpqzzcx6yf52oc35dgsoxfqkoxpy6kdmygbaja")
 
-	req, err := http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("Fail to get nonce")
-		t.Fail()
-	}
+	node.SetID(sender, mustAddress(t, "t09523"))
+	node.SetID(signer1, mustAddress(t, "t09524"))
+	node.SetID(signer2, mustAddress(t, "t09525"))
+	node.SetBalance(sender, abi.NewTokenAmount(1000000))
+	node.AddMultisig(mustAddress(t, MULTISIG_ADDRESS), []address.Address{sender, signer1}, 1, abi.NewTokenAmount(1000000))
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client := &http.Client{Timeout: time.Second * 60}
+	return lotusclient.NewClient(node.URL, "")
+}
 
-	// Send request
-	resp, err := client.Do(req)
+func mustAddress(t *testing.T, s string) address.Address {
+	addr, err := address.NewFromString(s)
 	if err != nil {
-		t.Errorf("Fail to get nonce")
+		t.Fatal(err)
 	}
+	return addr
+}
 
-	var res map[string]interface{}
-
-	t.Log(resp)
-
-	err = json.NewDecoder(resp.Body).Decode(&res)
+// getNonce returns the next nonce of an account
+func getNonce(t *testing.T, client *lotusclient.Client, account string) uint64 {
+	addr, err := address.NewFromString(account)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	t.Log(res["result"])
-
-	nonce := res["result"].(float64)
+	nonce, err := client.MpoolGetNonce(context.Background(), addr)
 	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+		t.Fatalf("Fail to get nonce: %v", err)
 	}
 
-	/* Create Transaction */
-
-	r := &RosettaConstructionFilecoin{false}
-	mtx := TxMetadata{
-		Nonce:      uint64(nonce),
-		GasFeeCap:  149794,
-		GasPremium: 149470,
-		GasLimit:   2180810,
-	}
-	pr := &PaymentRequest{
-		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
-		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
-		Quantity: 100000,
-		Metadata: mtx,
-	}
+	t.Log(nonce)
+	return nonce
+}
 
-	unsignedTxBase64, err := r.ConstructPayment(pr)
+// submitAndWait pushes a signed transaction, checks it is executed successfully and returns its receipt
+func submitAndWait(t *testing.T, client *lotusclient.Client, signedTx string) *types.MessageReceipt {
+	_, sm, err := DecodeTx(signedTx)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	signedTx, err := r.SignTx(unsignedTxBase64, sk)
+	msgCid, err := client.MpoolPush(context.Background(), sm)
 	if err != nil {
-		t.Error(err)
+		t.Fatalf("Fail to push message: %v", err)
 	}
 
-	t.Log(signedTx)
-
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolPush","id": 1, "params": [` + signedTx + `]}`)
+	t.Log(msgCid)
 
-	t.Log(string(data))
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Second)
+	defer cancel()
 
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
+	lookup, err := client.StateWaitMsg(ctx, msgCid, 1)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatalf("Fail to wait for message: %v", err)
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 60}
+	t.Log(lookup.Receipt)
 
-	// Send request
-	resp, err = client.Do(req)
-	if err != nil {
-		t.Errorf("FIX ME")
+	if lookup.Receipt.ExitCode != 0 {
+		t.Fatalf("message failed with exit code %d", lookup.Receipt.ExitCode)
 	}
 
-	var res2 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res2)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
+	return &lookup.Receipt
+}
 
-	t.Log(res2)
+// send from regular address
+func TestSendTransaction(t *testing.T) {
+	defer seq()()
 
-	if res2["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
+	/* Secret Key */
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
 
-	h, err := json.Marshal(res2["result"])
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
+	client := lotusClient(t)
 
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.StateWaitMsg","id": 1, "params": [` + string(h) + `, null]}`)
+	/* Get Nonce */
+	nonce := getNonce(t, client, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
 
-	t.Log(string(data))
+	/* Create Transaction */
 
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("FIX ME")
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	mtx := TxMetadata{
+		Nonce:      nonce,
+		GasFeeCap:  abi.NewTokenAmount(149794),
+		GasPremium: abi.NewTokenAmount(149470),
+		GasLimit:   2180810,
 	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 600}
-	// Send request
-	resp, err = client.Do(req)
-	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+	pr := &PaymentRequest{
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: mtx,
 	}
 
-	var res3 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res3)
+	unsignedTxBase64, err := r.ConstructPayment(pr)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	t.Log(res3)
-
-	if res3["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+	signedTx, err := r.SignTx(unsignedTxBase64, sk)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	var result = res3["result"].(map[string]interface{})
-	var receipt = result["Receipt"].(map[string]interface{})
-	var exitCode = receipt["ExitCode"].(float64)
-
-	if exitCode != 0 {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
+	t.Log(signedTx)
 
+	submitAndWait(t, client, signedTx)
 }
 
 // Send from multisig
@@ -503,56 +710,23 @@ func TestSendFromMultisig(t *testing.T) {
 	/* Secret Key */
 	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
 
-	/* Get Nonce */
-	data := []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolGetNonce","id": 1, "params": ["t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"]}`)
-
-	req, err := http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("Fail to get nonce")
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
+	client := lotusClient(t)
 
-	// Set client timeout
-	client := &http.Client{Timeout: time.Second * 60}
-
-	// Send request
-	resp, err := client.Do(req)
-	if err != nil {
-		t.Errorf("Fail to get nonce")
-	}
-
-	var res map[string]interface{}
-
-	t.Log(resp)
-
-	err = json.NewDecoder(resp.Body).Decode(&res)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	t.Log(res["result"])
-
-	nonce := res["result"].(float64)
-	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
+	/* Get Nonce */
+	nonce := getNonce(t, client, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
 
 	/* Create Transaction */
 
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	mtx := TxMetadata{
-		Nonce:      uint64(nonce),
-		GasFeeCap:  149794,
-		GasPremium: 149470,
+		Nonce:      nonce,
+		GasFeeCap:  abi.NewTokenAmount(149794),
+		GasPremium: abi.NewTokenAmount(149470),
 		GasLimit:   2180810,
 	}
 	params := MultisigPaymentParams{
 		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
-		Quantity: 1,
+		Quantity: abi.NewTokenAmount(1),
 	}
 	request := &MultisigPaymentRequest{
 		Multisig: MULTISIG_ADDRESS,
@@ -563,100 +737,17 @@ func TestSendFromMultisig(t *testing.T) {
 
 	unsignedTxBase64, err := r.ConstructMultisigPayment(request)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
 	signedTx, err := r.SignTx(unsignedTxBase64, sk)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	t.Log(signedTx)
 
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolPush","id": 1, "params": [` + signedTx + `]}`)
-
-	t.Log(string(data))
-
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 60}
-
-	// Send request
-	resp, err = client.Do(req)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	var res2 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res2)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	t.Log(res2)
-
-	if res2["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
-
-	h, err := json.Marshal(res2["result"])
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.StateWaitMsg","id": 1, "params": [` + string(h) + `, null]}`)
-
-	t.Log(string(data))
-
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 600}
-	// Send request
-	resp, err = client.Do(req)
-	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
-
-	var res3 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res3)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	t.Log(res3)
-
-	if res3["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
-
-	var result = res3["result"].(map[string]interface{})
-	var receipt = result["Receipt"].(map[string]interface{})
-	var exitCode = receipt["ExitCode"].(float64)
-
-	if exitCode != 0 {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
-
+	submitAndWait(t, client, signedTx)
 }
 
 // Key swap for a multisig
@@ -668,101 +759,56 @@ func TestSwapKeysMultisig(t *testing.T) {
 	sk2, _ := hex.DecodeString("8ad463d0fb5ab06172dd3c2b005c1d634e3a6576f8c1d6eb1796ba8d94c00469")
 
 	/* Addresses */
-	address := "t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy"
+	address1 := "t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy"
 	address2 := "t1itpqzzcx6yf52oc35dgsoxfqkoxpy6kdmygbaja"
 
 	addressID1 := "t09524"
 
-	/* Get Multisig signers */
-	data := []byte(`{"jsonrpc": "2.0","method": "Filecoin.StateReadState","id": 1, "params": ["` + MULTISIG_ADDRESS + `", null]}`)
-
-	t.Log(string(data))
+	client := lotusClient(t)
 
-	req, err := http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
+	/* Get Multisig signers */
+	multisig, err := address.NewFromString(MULTISIG_ADDRESS)
 	if err != nil {
-		t.Errorf("Fail to get nonce")
+		t.Fatal(err)
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client := &http.Client{Timeout: time.Second * 60}
-
-	// Send request
-	resp, err := client.Do(req)
+	actorState, err := client.StateReadState(context.Background(), multisig)
 	if err != nil {
-		t.Errorf("Fail to get nonce")
+		t.Fatalf("Fail to read multisig state: %v", err)
 	}
 
-	t.Log(resp)
-
-	var res map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res)
+	var state struct {
+		Signers []string
+	}
+	err = json.Unmarshal(actorState.State, &state)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	result := res["result"].(map[string]interface{})
-	state := result["State"].(map[string]interface{})
-	signers := state["Signers"].([]interface{})
+	t.Log(state.Signers)
 
 	var to, from string
 	var secretKey []byte
-	if signers[0] == addressID1 || signers[1] == addressID1 {
-		from = address
+	if state.Signers[0] == addressID1 || state.Signers[1] == addressID1 {
+		from = address1
 		to = address2
 		secretKey = sk
 	} else {
 		from = address2
-		to = address
+		to = address1
 		secretKey = sk2
 	}
 
 	/* Get Nonce */
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolGetNonce","id": 1, "params": ["` + from + `"]}`)
-
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
-	if err != nil {
-		t.Errorf("Fail to get nonce")
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
-
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 60}
-
-	// Send request
-	resp, err = client.Do(req)
-	if err != nil {
-		t.Errorf("Fail to get nonce")
-	}
-
-	var res1 map[string]interface{}
-
-	err = json.NewDecoder(resp.Body).Decode(&res1)
-	if err != nil {
-		t.Errorf("FIX ME")
-	}
-
-	t.Log(res["result"])
-
-	nonce := res1["result"].(float64)
-	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
-	}
+	nonce := getNonce(t, client, from)
 
 	/* Create Transaction */
 
-	r := &RosettaConstructionFilecoin{false}
+	r := &RosettaConstructionFilecoin{Mainnet: false}
 	mtx := TxMetadata{
-		Nonce:      uint64(nonce),
-		GasFeeCap:  149794,
-		GasPremium: 149470,
+		Nonce:      nonce,
+		GasFeeCap:  abi.NewTokenAmount(149794),
+		GasPremium: abi.NewTokenAmount(149470),
 		GasLimit:   2180810,
 	}
 	params := SwapAuthorizedPartyParams{
@@ -778,97 +824,198 @@ func TestSwapKeysMultisig(t *testing.T) {
 
 	unsignedTxBase64, err := r.ConstructSwapAuthorizedParty(request)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
 	signedTx, err := r.SignTx(unsignedTxBase64, secretKey)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	t.Log(signedTx)
 
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.MpoolPush","id": 1, "params": [` + signedTx + `]}`)
+	submitAndWait(t, client, signedTx)
+}
+
+// Create a multisig and send from it
+func TestCreateMultisig(t *testing.T) {
+	defer seq()()
 
-	t.Log(string(data))
+	/* Secret Key */
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	from := "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
 
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
+	client := lotusClient(t)
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+
+	/* Create Multisig */
+	request := &MultisigCreateRequest{
+		From:     from,
+		Quantity: abi.NewTokenAmount(1000),
+		Metadata: TxMetadata{
+			Nonce:      getNonce(t, client, from),
+			GasFeeCap:  abi.NewTokenAmount(149794),
+			GasPremium: abi.NewTokenAmount(149470),
+			GasLimit:   2180810,
+		},
+		Params: MultisigCreateParams{
+			Signers:   []string{from, "t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy"},
+			Threshold: 1,
+		},
+	}
+
+	unsignedTxBase64, err := r.ConstructMultisigCreate(request)
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
+	signedTx, err := r.SignTx(unsignedTxBase64, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 60}
+	receipt := submitAndWait(t, client, signedTx)
 
-	// Send request
-	resp, err = client.Do(req)
+	var created init_.ExecReturn
+	err = created.UnmarshalCBOR(bytes.NewReader(receipt.Return))
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	var res2 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res2)
-	if err != nil {
-		t.Errorf("FIX ME")
+	t.Log(created.IDAddress, created.RobustAddress)
+
+	/* Send from the new Multisig */
+	payment := &MultisigPaymentRequest{
+		Multisig: created.IDAddress.String(),
+		From:     from,
+		Metadata: TxMetadata{
+			Nonce:      getNonce(t, client, from),
+			GasFeeCap:  abi.NewTokenAmount(149794),
+			GasPremium: abi.NewTokenAmount(149470),
+			GasLimit:   2180810,
+		},
+		Params: MultisigPaymentParams{
+			To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+			Quantity: abi.NewTokenAmount(1),
+		},
 	}
 
-	t.Log(res2)
+	unsignedTxBase64, err = r.ConstructMultisigPayment(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if res2["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+	signedTx, err = r.SignTx(unsignedTxBase64, sk)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	h, err := json.Marshal(res2["result"])
+	submitAndWait(t, client, signedTx)
+}
+
+func TestApproveProposal(t *testing.T) {
+	defer seq()()
+
+	/* Secret Keys */
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	sk2, _ := hex.DecodeString("61b0cf875beaddf0429736e2c03b7a5a39e201d667f2d35c0b07013b6843c329")
+	from := "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+	approver := "t137sjdbgunloi7couiy4l5nc7pd6k2jmq32vizpy"
+
+	client := lotusClient(t)
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	gas := func(account string) TxMetadata {
+		return TxMetadata{
+			Nonce:      getNonce(t, client, account),
+			GasFeeCap:  abi.NewTokenAmount(149794),
+			GasPremium: abi.NewTokenAmount(149470),
+			GasLimit:   2180810,
+		}
+	}
+
+	/* Create a 2 of 2 Multisig */
+	unsignedTxBase64, err := r.ConstructMultisigCreate(&MultisigCreateRequest{
+		From:     from,
+		Quantity: abi.NewTokenAmount(1000),
+		Metadata: gas(from),
+		Params: MultisigCreateParams{
+			Signers:   []string{from, approver},
+			Threshold: 2,
+		},
+	})
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	data = []byte(`{"jsonrpc": "2.0","method": "Filecoin.StateWaitMsg","id": 1, "params": [` + string(h) + `, null]}`)
+	signedTx, err := r.SignTx(unsignedTxBase64, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	t.Log(string(data))
+	var created init_.ExecReturn
+	err = created.UnmarshalCBOR(bytes.NewReader(submitAndWait(t, client, signedTx).Return))
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	req, err = http.NewRequest("POST", os.Getenv("LOTUS_URL"), bytes.NewBuffer(data))
+	/* Propose */
+	proposal, err := r.ConstructMultisigPayment(&MultisigPaymentRequest{
+		Multisig: created.IDAddress.String(),
+		From:     from,
+		Metadata: gas(from),
+		Params: MultisigPaymentParams{
+			To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+			Quantity: abi.NewTokenAmount(1),
+		},
+	})
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+os.Getenv("LOTUS_JWT"))
+	signedTx, err = r.SignTx(proposal, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	// Set client timeout
-	client = &http.Client{Timeout: time.Second * 600}
-	// Send request
-	resp, err = client.Do(req)
+	proposed, err := DecodeProposeReceipt(submitAndWait(t, client, signedTx))
 	if err != nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+		t.Fatal(err)
+	}
+
+	if proposed.Status() != OperationStatusPending {
+		t.Fatalf("the proposal should wait for the approval: %+v", proposed)
 	}
 
-	var res3 map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&res3)
+	/* Approve the proposal with its hash */
+	requester, err := client.StateLookupID(context.Background(), mustAddress(t, from))
 	if err != nil {
-		t.Errorf("FIX ME")
+		t.Fatal(err)
 	}
 
-	t.Log(res3)
+	unsignedTxBase64, err = r.ConstructMultisigApproveProposal(&MultisigProposalRequest{
+		From:     approver,
+		Metadata: gas(approver),
+		Params: MultisigProposalParams{
+			TxnID:     proposed.TxnID,
+			Proposal:  proposal,
+			Requester: requester.String(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if res3["result"] == nil {
-		t.Errorf("FIX ME")
-		t.FailNow()
+	signedTx, err = r.SignTx(unsignedTxBase64, sk2)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	result = res3["result"].(map[string]interface{})
-	receipt := result["Receipt"].(map[string]interface{})
-	exitCode := receipt["ExitCode"].(float64)
+	approved, err := DecodeApproveReceipt(submitAndWait(t, client, signedTx))
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if exitCode != 0 {
-		t.Errorf("FIX ME")
-		t.FailNow()
+	if approved.Status() != OperationStatusOk {
+		t.Errorf("the approved proposal should be applied: %+v", approved)
 	}
 }
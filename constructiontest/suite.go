@@ -0,0 +1,1753 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+
+// Package constructiontest provides a test suite that any implementation of
+// rosettaFilecoinLib.RosettaConstructionTool (mocks, remote signers, hardware-backed
+// signers...) can run to check it behaves like the reference implementation.
+//
+// The suite is testnet only: its vectors are t prefixed addresses, so the tool must be configured for testnet
+// (e.g. RosettaConstructionFilecoin{Mainnet: false}).
+package constructiontest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/minio/blake2b-simd"
+
+	rosettaFilecoinLib "github.com/zondax/rosetta-filecoin-lib"
+)
+
+// Test vectors shared by every case of the suite, the addresses are testnet addresses
+const (
+	SecretKey    = "f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a"
+	BLSSecretKey = "2a3c5b6f2e1d7a8b9c0d1e2f3a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c06"
+	BLSPublicKey = "95a891ef8ba0ba004734d366bd8456d1a7f712571b0d71759fd4d9a448105205949f6579e473b3b0dc4759bc8c68aedc"
+	BLSAddress   = "t3swujd34luc5aarzu2ntl3bcw2gt7oesxdmgxc5m72tm2isaqkiczjh3fphshhm5q3rdvtpemncxnz4ypcyuq"
+	PublicKey    = "0435e752dc6b4113f78edcf2cf7b8082e442021de5f00818f555397a6f181af795ace98f0f7d065793eaffa1b06bf52e572c97030c53a2396dfab40ba0e976b108"
+	Address      = "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+	To           = "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy"
+	Multisig     = "t01002"
+	NewSigner    = "t14q6mgxil4ism6a6vp2ee375wfjyionl46wtle5q"
+	AddressID    = "t01234"
+	unsignedTx   = "8A005501FD1D0F4DFCD7E99AFCB99A8326B7DC459D32C6285501B882619D46558F3D9E316D11B48DCF211327025A0144000186A01961A84200014200010040"
+)
+
+// Run executes the whole suite against tool, which must be configured for testnet
+func Run(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	if addr, err := tool.DeriveFromPublicKey(mustHex(t, PublicKey)); err == nil && !strings.HasPrefix(addr, address.TestnetPrefix) {
+		t.Fatalf("the suite runs on testnet but the tool derives %s, configure it for testnet", addr)
+	}
+
+	t.Run("DeriveFromPublicKey", func(t *testing.T) { testDeriveFromPublicKey(t, tool) })
+	t.Run("SignVerify", func(t *testing.T) { testSignVerify(t, tool) })
+	t.Run("SignVerifyBLS", func(t *testing.T) { testSignVerifyBLS(t, tool) })
+	t.Run("ConstructPayment", func(t *testing.T) { testConstructPayment(t, tool) })
+	t.Run("ConstructMultisigPayment", func(t *testing.T) { testConstructMultisigPayment(t, tool) })
+	t.Run("ConstructSwapAuthorizedParty", func(t *testing.T) { testConstructSwapAuthorizedParty(t, tool) })
+	t.Run("ConstructSignerManagement", func(t *testing.T) { testConstructSignerManagement(t, tool) })
+	t.Run("ConstructLockBalance", func(t *testing.T) { testConstructLockBalance(t, tool) })
+	t.Run("ConstructMultisigCreate", func(t *testing.T) { testConstructMultisigCreate(t, tool) })
+	t.Run("ConstructMultisigApproveCancel", func(t *testing.T) { testConstructMultisigApproveCancel(t, tool) })
+	t.Run("ProposalHash", func(t *testing.T) { testProposalHash(t, tool) })
+	t.Run("ConstructMethodCall", func(t *testing.T) { testConstructMethodCall(t, tool) })
+	t.Run("ConstructMiner", func(t *testing.T) { testConstructMiner(t, tool) })
+	t.Run("ConstructMarket", func(t *testing.T) { testConstructMarket(t, tool) })
+	t.Run("ConstructPaych", func(t *testing.T) { testConstructPaych(t, tool) })
+	t.Run("Voucher", func(t *testing.T) { testVoucher(t, tool) })
+	t.Run("ConstructVerifreg", func(t *testing.T) { testConstructVerifreg(t, tool) })
+	t.Run("Operations", func(t *testing.T) { testOperations(t, tool) })
+	t.Run("SigningPayloadCombine", func(t *testing.T) { testSigningPayloadCombine(t, tool) })
+	t.Run("SignTx", func(t *testing.T) { testSignTx(t, tool) })
+	t.Run("ParseTx", func(t *testing.T) { testParseTx(t, tool) })
+	t.Run("ParseTxDetailed", func(t *testing.T) { testParseTxDetailed(t, tool) })
+	t.Run("SummarizeTx", func(t *testing.T) { testSummarizeTx(t, tool) })
+	t.Run("MaxFee", func(t *testing.T) { testMaxFee(t, tool) })
+	t.Run("Hash", func(t *testing.T) { testHash(t, tool) })
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid test vector: %v", err)
+	}
+	return b
+}
+
+func mustAddress(t *testing.T, s string) address.Address {
+	addr, err := address.NewFromString(s)
+	if err != nil {
+		t.Fatalf("invalid test address %s: %v", s, err)
+	}
+	return addr
+}
+
+func metadata() rosettaFilecoinLib.TxMetadata {
+	return rosettaFilecoinLib.TxMetadata{
+		Nonce:      1,
+		GasFeeCap:  abi.NewTokenAmount(1),
+		GasPremium: abi.NewTokenAmount(1),
+		GasLimit:   25000,
+	}
+}
+
+// Transactions are base64 encoded Lotus JSON or CBOR
+func decodeTx(t *testing.T, tx string) []byte {
+	b, err := base64.StdEncoding.DecodeString(tx)
+	if err != nil {
+		t.Fatalf("transaction is not base64: %v", err)
+	}
+	return b
+}
+
+func decodeUnsignedTx(t *testing.T, tx string) *types.Message {
+	b := decodeTx(t, tx)
+
+	var msg types.Message
+	err := json.Unmarshal(b, &msg)
+	if err != nil {
+		err = msg.UnmarshalCBOR(bytes.NewReader(b))
+	}
+	if err != nil {
+		t.Fatalf("unsigned transaction is not a message: %v", err)
+	}
+	return &msg
+}
+
+func decodeSignedTx(t *testing.T, tx string) *types.SignedMessage {
+	b := decodeTx(t, tx)
+
+	var sm types.SignedMessage
+	err := json.Unmarshal(b, &sm)
+	if err != nil {
+		err = sm.UnmarshalCBOR(bytes.NewReader(b))
+	}
+	if err != nil {
+		t.Fatalf("signed transaction is not a signed message: %v", err)
+	}
+	return &sm
+}
+
+func decodeProposeParams(t *testing.T, msg *types.Message) *multisig.ProposeParams {
+	if msg.Method != builtin.MethodsMultisig.Propose {
+		t.Fatalf("expected Propose method, got %d", msg.Method)
+	}
+
+	var params multisig.ProposeParams
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		t.Fatalf("params are not ProposeParams: %v", err)
+	}
+	return &params
+}
+
+// checkSummary checks the English summary of tx, built with metadata()
+func checkSummary(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool, tx string, action string) {
+	t.Helper()
+
+	summary, err := tool.SummarizeTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := action + ", max fee 0.000000000000025 FIL, nonce 1"
+	if summary.Text != expected {
+		t.Errorf("unexpected summary %q, expected %q", summary.Text, expected)
+	}
+}
+
+// withValue returns the unsigned tx with the value of its message replaced, as base64 encoded JSON
+func withValue(t *testing.T, tx string, value abi.TokenAmount) string {
+	msg := decodeUnsignedTx(t, tx)
+	msg.Value = value
+	return encodeMessage(t, msg)
+}
+
+// withProposedValue returns the unsigned multisig proposal tx with the value of the proposed call replaced
+func withProposedValue(t *testing.T, tx string, value abi.TokenAmount) string {
+	msg := decodeUnsignedTx(t, tx)
+
+	var params multisig.ProposeParams
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		t.Fatalf("transaction is not a proposal: %v", err)
+	}
+	params.Value = value
+
+	buf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+	msg.Params = buf.Bytes()
+	return encodeMessage(t, msg)
+}
+
+func encodeMessage(t *testing.T, msg *types.Message) string {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func checkHeader(t *testing.T, msg *types.Message, to string) {
+	if msg.To != mustAddress(t, to) {
+		t.Errorf("unexpected To %s", msg.To)
+	}
+	if msg.From != mustAddress(t, Address) {
+		t.Errorf("unexpected From %s", msg.From)
+	}
+	if msg.Nonce != 1 || msg.GasLimit != 25000 || msg.GasFeeCap.Int64() != 1 || msg.GasPremium.Int64() != 1 {
+		t.Errorf("metadata not applied: %+v", msg)
+	}
+}
+
+func testDeriveFromPublicKey(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	addr, err := tool.DeriveFromPublicKey(mustHex(t, PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mustAddress(t, addr) != mustAddress(t, Address) {
+		t.Errorf("derived %s, expected %s", addr, Address)
+	}
+
+	addr, err = tool.DeriveFromPublicKey(mustHex(t, BLSPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mustAddress(t, addr) != mustAddress(t, BLSAddress) {
+		t.Errorf("derived %s, expected %s", addr, BLSAddress)
+	}
+}
+
+func testSignVerify(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	message := []byte("rosetta-filecoin-lib")
+
+	sig, err := tool.Sign(message, mustHex(t, SecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tool.Verify(message, mustHex(t, PublicKey), sig); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+
+	if err := tool.Verify([]byte("tampered"), mustHex(t, PublicKey), sig); err == nil {
+		t.Error("signature of another message should not verify")
+	}
+}
+
+func testSignVerifyBLS(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	message := []byte("rosetta-filecoin-lib")
+
+	sig, err := tool.SignWithType(message, mustHex(t, BLSSecretKey), crypto.SigTypeBLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tool.Verify(message, mustHex(t, BLSPublicKey), sig); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+
+	if err := tool.Verify([]byte("tampered"), mustHex(t, BLSPublicKey), sig); err == nil {
+		t.Error("signature of another message should not verify")
+	}
+}
+
+func testConstructPayment(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	tx, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, tx)
+	checkHeader(t, msg, To)
+	if msg.Method != builtin.MethodSend || msg.Value.Int64() != 100000 {
+		t.Errorf("unexpected payment %+v", msg)
+	}
+
+	if _, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{From: Address, To: "invalid"}); err == nil {
+		t.Error("invalid destination should fail")
+	}
+}
+
+func testConstructMultisigPayment(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	tx, err := tool.ConstructMultisigPayment(&rosettaFilecoinLib.MultisigPaymentRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MultisigPaymentParams{
+			To:       To,
+			Quantity: abi.NewTokenAmount(1000),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, tx)
+	checkHeader(t, msg, Multisig)
+
+	params := decodeProposeParams(t, msg)
+	if params.To != mustAddress(t, To) || params.Value.Int64() != 1000 || params.Method != builtin.MethodSend {
+		t.Errorf("unexpected proposal %+v", params)
+	}
+}
+
+func testConstructSwapAuthorizedParty(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	tx, err := tool.ConstructSwapAuthorizedParty(&rosettaFilecoinLib.SwapAuthorizedPartyRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.SwapAuthorizedPartyParams{
+			From: Address,
+			To:   NewSigner,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, tx)
+	checkHeader(t, msg, Multisig)
+
+	params := decodeProposeParams(t, msg)
+	if params.To != mustAddress(t, Multisig) || params.Method != builtin.MethodsMultisig.SwapSigner {
+		t.Fatalf("unexpected proposal %+v", params)
+	}
+
+	var swap multisig.SwapSignerParams
+	if err := swap.UnmarshalCBOR(bytes.NewReader(params.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if swap.From != mustAddress(t, Address) || swap.To != mustAddress(t, NewSigner) {
+		t.Errorf("unexpected swap %+v", swap)
+	}
+}
+
+func testConstructSignerManagement(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	add, err := tool.ConstructAddSigner(&rosettaFilecoinLib.AddSignerRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.AddSignerParams{Signer: NewSigner, Increase: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := decodeProposeParams(t, decodeUnsignedTx(t, add))
+	var addParams multisig.AddSignerParams
+	if err := addParams.UnmarshalCBOR(bytes.NewReader(params.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if params.To != mustAddress(t, Multisig) || params.Method != builtin.MethodsMultisig.AddSigner ||
+		addParams.Signer != mustAddress(t, NewSigner) || !addParams.Increase {
+		t.Errorf("unexpected AddSigner proposal %+v %+v", params, addParams)
+	}
+
+	remove, err := tool.ConstructRemoveSigner(&rosettaFilecoinLib.RemoveSignerRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.RemoveSignerParams{Signer: NewSigner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params = decodeProposeParams(t, decodeUnsignedTx(t, remove))
+	var removeParams multisig.RemoveSignerParams
+	if err := removeParams.UnmarshalCBOR(bytes.NewReader(params.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if params.To != mustAddress(t, Multisig) || params.Method != builtin.MethodsMultisig.RemoveSigner ||
+		removeParams.Signer != mustAddress(t, NewSigner) || removeParams.Decrease {
+		t.Errorf("unexpected RemoveSigner proposal %+v %+v", params, removeParams)
+	}
+
+	threshold, err := tool.ConstructChangeNumApprovalsThreshold(&rosettaFilecoinLib.ChangeNumApprovalsThresholdRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.ChangeNumApprovalsThresholdParams{NewThreshold: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params = decodeProposeParams(t, decodeUnsignedTx(t, threshold))
+	var thresholdParams multisig.ChangeNumApprovalsThresholdParams
+	if err := thresholdParams.UnmarshalCBOR(bytes.NewReader(params.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if params.To != mustAddress(t, Multisig) || params.Method != builtin.MethodsMultisig.ChangeNumApprovalsThreshold ||
+		thresholdParams.NewThreshold != 2 {
+		t.Errorf("unexpected ChangeNumApprovalsThreshold proposal %+v %+v", params, thresholdParams)
+	}
+
+	_, err = tool.ConstructChangeNumApprovalsThreshold(&rosettaFilecoinLib.ChangeNumApprovalsThresholdRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+	})
+	if err == nil {
+		t.Error("a zero threshold should be rejected")
+	}
+}
+
+func testConstructLockBalance(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	unsignedTx, err := tool.ConstructLockBalance(&rosettaFilecoinLib.LockBalanceRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.LockBalanceParams{
+			StartEpoch:     100,
+			UnlockDuration: 1000,
+			Amount:         abi.NewTokenAmount(5000),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := decodeProposeParams(t, decodeUnsignedTx(t, unsignedTx))
+	var lockParams multisig.LockBalanceParams
+	if err := lockParams.UnmarshalCBOR(bytes.NewReader(params.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if params.To != mustAddress(t, Multisig) || params.Method != builtin.MethodsMultisig.LockBalance ||
+		lockParams.StartEpoch != 100 || lockParams.UnlockDuration != 1000 || !lockParams.Amount.Equals(abi.NewTokenAmount(5000)) {
+		t.Errorf("unexpected LockBalance proposal %+v %+v", params, lockParams)
+	}
+
+	_, err = tool.ConstructLockBalance(&rosettaFilecoinLib.LockBalanceRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.LockBalanceParams{Amount: abi.NewTokenAmount(5000)},
+	})
+	if err == nil {
+		t.Error("a zero unlock duration should be rejected")
+	}
+}
+
+func testConstructMultisigCreate(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	request := &rosettaFilecoinLib.MultisigCreateRequest{
+		From:     Address,
+		Quantity: abi.NewTokenAmount(1000),
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MultisigCreateParams{
+			Signers:        []string{Address, NewSigner},
+			Threshold:      2,
+			UnlockDuration: 100,
+		},
+	}
+
+	tx, err := tool.ConstructMultisigCreate(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, tx)
+	checkHeader(t, msg, "t01")
+	if msg.Method != builtin.MethodsInit.Exec || !msg.Value.Equals(abi.NewTokenAmount(1000)) {
+		t.Fatalf("unexpected method %d or value %s", msg.Method, msg.Value)
+	}
+
+	var params init_.ExecParams
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if params.CodeCID != builtin.MultisigActorCodeID {
+		t.Fatalf("unexpected code %s", params.CodeCID)
+	}
+
+	var constructorParams multisig.ConstructorParams
+	if err := constructorParams.UnmarshalCBOR(bytes.NewReader(params.ConstructorParams)); err != nil {
+		t.Fatal(err)
+	}
+	if len(constructorParams.Signers) != 2 || constructorParams.Signers[1] != mustAddress(t, NewSigner) ||
+		constructorParams.NumApprovalsThreshold != 2 || constructorParams.UnlockDuration != 100 {
+		t.Errorf("unexpected constructor params %+v", constructorParams)
+	}
+
+	invalid := *request
+	invalid.Params.Threshold = 3
+	if _, err := tool.ConstructMultisigCreate(&invalid); err == nil {
+		t.Error("a threshold above the number of signers should be rejected")
+	}
+
+	invalid = *request
+	invalid.Params.Signers = []string{Address, Address}
+	if _, err := tool.ConstructMultisigCreate(&invalid); err == nil {
+		t.Error("duplicate signers should be rejected")
+	}
+
+	invalid = *request
+	invalid.Params.StartEpoch = 10
+	if _, err := tool.ConstructMultisigCreate(&invalid); err == nil {
+		t.Error("a start epoch should be rejected")
+	}
+}
+
+func testProposalHash(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	proposal, err := tool.ConstructMultisigPayment(&rosettaFilecoinLib.MultisigPaymentRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MultisigPaymentParams{
+			To:       To,
+			Quantity: abi.NewTokenAmount(100),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := multisig.ComputeProposalHash(&multisig.Transaction{
+		To:       mustAddress(t, To),
+		Value:    abi.NewTokenAmount(100),
+		Method:   builtin.MethodSend,
+		Params:   []byte{},
+		Approved: []address.Address{mustAddress(t, AddressID)},
+	}, blake2b.Sum256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := tool.ComputeProposalHash(proposal, AddressID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash, expected) {
+		t.Errorf("expected proposal hash %x, got %x", expected, hash)
+	}
+
+	if _, err := tool.ComputeProposalHash(proposal, ""); err == nil {
+		t.Error("the requester should be required when the proposer is not an ID address")
+	}
+
+	if _, err := tool.ComputeProposalHash(unsignedTx, AddressID); err == nil {
+		t.Error("a payment is not a proposal")
+	}
+
+	request := &rosettaFilecoinLib.MultisigProposalRequest{
+		From:     NewSigner,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MultisigProposalParams{
+			TxnID:     7,
+			Proposal:  proposal,
+			Requester: AddressID,
+		},
+	}
+
+	approve, err := tool.ConstructMultisigApproveProposal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.From = Address
+	cancel, err := tool.ConstructMultisigCancelProposal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		tx     string
+		from   string
+		method abi.MethodNum
+	}{
+		{approve, NewSigner, builtin.MethodsMultisig.Approve},
+		{cancel, Address, builtin.MethodsMultisig.Cancel},
+	} {
+		msg := decodeUnsignedTx(t, tc.tx)
+
+		var params multisig.TxnIDParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			t.Fatal(err)
+		}
+		if msg.To != mustAddress(t, Multisig) || msg.From != mustAddress(t, tc.from) || msg.Method != tc.method ||
+			params.ID != 7 || !bytes.Equal(params.ProposalHash, expected) {
+			t.Errorf("unexpected call %+v %+v", msg, params)
+		}
+	}
+}
+
+func testConstructMultisigApproveCancel(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	proposalHash := bytes.Repeat([]byte{0xab}, 32)
+
+	approve, err := tool.ConstructMultisigApprove(&rosettaFilecoinLib.MultisigApproveRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: 3, ProposalHash: proposalHash},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel, err := tool.ConstructMultisigCancel(&rosettaFilecoinLib.MultisigCancelRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		tx     string
+		method abi.MethodNum
+		id     multisig.TxnID
+		hash   []byte
+	}{
+		{approve, builtin.MethodsMultisig.Approve, 3, proposalHash},
+		{cancel, builtin.MethodsMultisig.Cancel, 4, nil},
+	} {
+		msg := decodeUnsignedTx(t, tc.tx)
+		checkHeader(t, msg, Multisig)
+
+		if msg.Method != tc.method || !msg.Value.IsZero() {
+			t.Fatalf("unexpected method %d or value %s", msg.Method, msg.Value)
+		}
+
+		var params multisig.TxnIDParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			t.Fatal(err)
+		}
+		if params.ID != tc.id || !bytes.Equal(params.ProposalHash, tc.hash) {
+			t.Errorf("unexpected params %+v", params)
+		}
+	}
+
+	_, err = tool.ConstructMultisigApprove(&rosettaFilecoinLib.MultisigApproveRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: 3, ProposalHash: []byte{1, 2, 3}},
+	})
+	if err == nil {
+		t.Error("a truncated proposal hash should be rejected")
+	}
+
+	_, err = tool.ConstructMultisigCancel(&rosettaFilecoinLib.MultisigCancelRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: -1},
+	})
+	if err == nil {
+		t.Error("a negative transaction ID should be rejected")
+	}
+}
+
+func testOperations(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	cases := map[string][]rosettaFilecoinLib.Operation{
+		"Send": {
+			{Type: rosettaFilecoinLib.OperationSend, Account: Address, Amount: abi.NewTokenAmount(-100000)},
+			{Type: rosettaFilecoinLib.OperationSend, Account: To, Amount: abi.NewTokenAmount(100000)},
+		},
+		"Propose": {{
+			Type:    rosettaFilecoinLib.OperationPropose,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(100000),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig: Multisig,
+				rosettaFilecoinLib.MetadataTo:       To,
+			},
+		}},
+		"SwapSigner": {{
+			Type:    rosettaFilecoinLib.OperationSwapSigner,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig:  Multisig,
+				rosettaFilecoinLib.MetadataOldSigner: Address,
+				rosettaFilecoinLib.MetadataNewSigner: NewSigner,
+			},
+		}},
+		"AddSigner": {{
+			Type:    rosettaFilecoinLib.OperationAddSigner,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig: Multisig,
+				rosettaFilecoinLib.MetadataSigner:   NewSigner,
+				rosettaFilecoinLib.MetadataIncrease: "true",
+			},
+		}},
+		"RemoveSigner": {{
+			Type:    rosettaFilecoinLib.OperationRemoveSigner,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig: Multisig,
+				rosettaFilecoinLib.MetadataSigner:   NewSigner,
+			},
+		}},
+		"ChangeNumApprovalsThreshold": {{
+			Type:    rosettaFilecoinLib.OperationChangeNumApprovalsThreshold,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig:     Multisig,
+				rosettaFilecoinLib.MetadataNewThreshold: "2",
+			},
+		}},
+		"LockBalance": {{
+			Type:    rosettaFilecoinLib.OperationLockBalance,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig:       Multisig,
+				rosettaFilecoinLib.MetadataStartEpoch:     "100",
+				rosettaFilecoinLib.MetadataUnlockDuration: "1000",
+				rosettaFilecoinLib.MetadataLockAmount:     "5000",
+			},
+		}},
+		"MarketAddBalance": {{
+			Type:     rosettaFilecoinLib.OperationMarketAddBalance,
+			Account:  Address,
+			Amount:   abi.NewTokenAmount(1000),
+			Metadata: map[string]string{rosettaFilecoinLib.MetadataProviderOrClient: To},
+		}},
+		"MarketWithdrawBalance": {{
+			Type:     rosettaFilecoinLib.OperationMarketWithdrawBalance,
+			Account:  Address,
+			Amount:   abi.NewTokenAmount(1000),
+			Metadata: map[string]string{rosettaFilecoinLib.MetadataProviderOrClient: Address},
+		}},
+		"CreateMultisig": {{
+			Type:    rosettaFilecoinLib.OperationCreateMultisig,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(1000),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataSigners:        Address + "," + NewSigner,
+				rosettaFilecoinLib.MetadataThreshold:      "1",
+				rosettaFilecoinLib.MetadataUnlockDuration: "100",
+			},
+		}},
+		"Approve": {{
+			Type:    rosettaFilecoinLib.OperationApprove,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig:     Multisig,
+				rosettaFilecoinLib.MetadataTxnID:        "3",
+				rosettaFilecoinLib.MetadataProposalHash: "abababababababababababababababababababababababababababababababab",
+			},
+		}},
+		"Cancel": {{
+			Type:    rosettaFilecoinLib.OperationCancel,
+			Account: Address,
+			Amount:  abi.NewTokenAmount(0),
+			Metadata: map[string]string{
+				rosettaFilecoinLib.MetadataMultisig: Multisig,
+				rosettaFilecoinLib.MetadataTxnID:    "4",
+			},
+		}},
+	}
+
+	for name, operations := range cases {
+		t.Run(name, func(t *testing.T) {
+			tx, err := tool.ConstructFromOperations(operations, metadata())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := tool.ParseToOperations(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Amounts are compared through their JSON representation
+			expected, _ := json.Marshal(operations)
+			actual, _ := json.Marshal(parsed)
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("parsed operations %s differ from %s", actual, expected)
+			}
+		})
+	}
+
+	unbalanced := []rosettaFilecoinLib.Operation{
+		{Type: rosettaFilecoinLib.OperationSend, Account: Address, Amount: abi.NewTokenAmount(-100000)},
+		{Type: rosettaFilecoinLib.OperationSend, Account: To, Amount: abi.NewTokenAmount(1)},
+	}
+	if _, err := tool.ConstructFromOperations(unbalanced, metadata()); err == nil {
+		t.Error("unbalanced Send operations should be rejected")
+	}
+
+	if _, err := tool.ConstructFromOperations([]rosettaFilecoinLib.Operation{{Type: "Unknown", Account: Address}}, metadata()); err == nil {
+		t.Error("unknown operation types should be rejected")
+	}
+
+	// The method numbers of multisig Propose, Approve and Cancel are used by other actors
+	changePeerID, err := tool.ConstructMinerChangePeerID(&rosettaFilecoinLib.MinerChangePeerIDRequest{
+		Miner:    "t01000",
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MinerChangePeerIDParams{NewID: "12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settle, err := tool.ConstructPaychSettle(&rosettaFilecoinLib.PaychRequest{Channel: "t01005", From: Address, Metadata: metadata()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	power, err := tool.ConstructMethodCall(&rosettaFilecoinLib.MethodCallRequest{
+		From:     Address,
+		To:       rosettaFilecoinLib.FormatAddress(builtin.StoragePowerActorAddr, false),
+		Method:   uint64(builtin.MethodsPower.CreateMiner),
+		Params:   []byte{0x80},
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tx := range map[string]string{"miner ChangePeerID": changePeerID, "paych Settle": settle, "power CreateMiner": power} {
+		_, err := tool.ParseToOperations(tx)
+		if err == nil || !strings.Contains(err.Error(), "unsupported") {
+			t.Errorf("%s should be an unsupported call: %v", name, err)
+		}
+	}
+}
+
+func testSigningPayloadCombine(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	unsigned, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := tool.ConstructSigningPayload(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.SignatureType != crypto.SigTypeSecp256k1 {
+		t.Errorf("unexpected signature type %d", payload.SignatureType)
+	}
+	if !bytes.Equal(payload.Bytes, decodeUnsignedTx(t, unsigned).Cid().Bytes()) {
+		t.Error("payload should be the message cid bytes")
+	}
+
+	sig, err := tool.Sign(payload.Bytes, mustHex(t, SecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := tool.CombineTx(unsigned, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decodeSignedTx(t, signed).Message.Cid() != decodeUnsignedTx(t, unsigned).Cid() {
+		t.Error("signed message differs from the unsigned transaction")
+	}
+
+	sig[0] ^= 0xff
+	if _, err := tool.CombineTx(unsigned, sig); err == nil {
+		t.Error("invalid signature should be rejected")
+	}
+}
+
+func testSignTx(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	unsigned, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := tool.SignTx(unsigned, mustHex(t, SecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm := decodeSignedTx(t, signed)
+	if sm.Message.Cid() != decodeUnsignedTx(t, unsigned).Cid() {
+		t.Error("signed message differs from the unsigned transaction")
+	}
+
+	if err := tool.Verify(sm.Message.Cid().Bytes(), mustHex(t, PublicKey), sm.Signature.Data); err != nil {
+		t.Errorf("signature should verify: %v", err)
+	}
+}
+
+func testParseTx(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	raw := mustHex(t, unsignedTx)
+	expected, err := types.DecodeMessage(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := tool.ParseTx(base64.StdEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decodeUnsignedTx(t, parsed).Cid() != expected.Cid() {
+		t.Error("parsed transaction differs from the input")
+	}
+
+	// Any output of the tool is a valid input
+	unsigned, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err = tool.ParseTx(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decodeUnsignedTx(t, parsed).Cid() != decodeUnsignedTx(t, unsigned).Cid() {
+		t.Error("parsed transaction differs from the constructed one")
+	}
+
+	if _, err := tool.ParseTx("not a transaction"); err == nil {
+		t.Error("garbage input should fail")
+	}
+}
+
+func testParseTxDetailed(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	payment, err := tool.ConstructMultisigPayment(&rosettaFilecoinLib.MultisigPaymentRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MultisigPaymentParams{To: To, Quantity: abi.NewTokenAmount(500)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swap, err := tool.ConstructSwapAuthorizedParty(&rosettaFilecoinLib.SwapAuthorizedPartyRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.SwapAuthorizedPartyParams{From: Address, To: NewSigner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create, err := tool.ConstructMultisigCreate(&rosettaFilecoinLib.MultisigCreateRequest{
+		From:     Address,
+		Quantity: abi.NewTokenAmount(1000),
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MultisigCreateParams{Signers: []string{Address, NewSigner}, Threshold: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approve, err := tool.ConstructMultisigApprove(&rosettaFilecoinLib.MultisigApproveRequest{
+		Multisig: Multisig,
+		From:     NewSigner,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: 3, ProposalHash: bytes.Repeat([]byte{0xab}, 32)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		tx         string
+		actor      string
+		methodName string
+		params     string
+	}{
+		{payment, rosettaFilecoinLib.ActorMultisig, "Propose",
+			`{"proposal":{"to":"` + To + `","value":"500","method":0,"method_name":"Send"}}`},
+		{swap, rosettaFilecoinLib.ActorMultisig, "Propose",
+			`{"proposal":{"to":"` + Multisig + `","value":"0","method":7,"actor":"multisig","method_name":"SwapSigner",` +
+				`"params":{"from":"` + Address + `","to":"` + NewSigner + `"}}}`},
+		{create, rosettaFilecoinLib.ActorInit, "Exec",
+			`{"code":"fil/1/multisig","code_cid":"` + builtin.MultisigActorCodeID.String() + `",` +
+				`"constructor_params":{"signers":["` + Address + `","` + NewSigner + `"],"threshold":2,"unlock_duration":0}}`},
+		{approve, rosettaFilecoinLib.ActorMultisig, "Approve",
+			`{"proposal_hash":"` + hex.EncodeToString(bytes.Repeat([]byte{0xab}, 32)) + `","txn_id":3}`},
+	}
+
+	for _, c := range cases {
+		parsed, err := tool.ParseTxDetailed(c.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		params, err := json.Marshal(parsed.Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.Actor != c.actor || parsed.MethodName != c.methodName || string(params) != c.params {
+			t.Errorf("unexpected parsed %s call of %s: %s", parsed.MethodName, parsed.Actor, params)
+		}
+		if parsed.From != decodeUnsignedTx(t, c.tx).From.String() || parsed.Signature != nil {
+			t.Errorf("unexpected parsed transaction %+v", parsed)
+		}
+	}
+
+	signed, err := tool.SignTx(payment, mustHex(t, SecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := tool.ParseTxDetailed(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Signature == nil || parsed.MethodName != "Propose" {
+		t.Errorf("unexpected parsed signed transaction %+v", parsed)
+	}
+
+	// Unknown params are kept as they are
+	msg := decodeUnsignedTx(t, payment)
+	msg.Method = 100
+	unknown, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err = tool.ParseTxDetailed(string(unknown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "" || parsed.Params != nil || !bytes.Equal(parsed.RawParams, msg.Params) {
+		t.Errorf("unexpected parsed unknown method %+v", parsed)
+	}
+}
+
+func testSummarizeTx(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	payment, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: rosettaFilecoinLib.MustParseFIL("1.5"),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposal, err := tool.ConstructMultisigPayment(&rosettaFilecoinLib.MultisigPaymentRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MultisigPaymentParams{To: To, Quantity: rosettaFilecoinLib.MustParseFIL("12.5")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addSigner, err := tool.ConstructAddSigner(&rosettaFilecoinLib.AddSignerRequest{
+		Multisig: Multisig,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.AddSignerParams{Signer: NewSigner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create, err := tool.ConstructMultisigCreate(&rosettaFilecoinLib.MultisigCreateRequest{
+		From:     Address,
+		Quantity: rosettaFilecoinLib.MustParseFIL("100"),
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MultisigCreateParams{Signers: []string{Address, NewSigner}, Threshold: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approve, err := tool.ConstructMultisigApprove(&rosettaFilecoinLib.MultisigApproveRequest{
+		Multisig: Multisig,
+		From:     NewSigner,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.TxnIDParams{TxnID: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, payment)
+	msg.Method = 100
+	call, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A proposal carrying value of its own
+	valuedProposal := withValue(t, addSigner, rosettaFilecoinLib.MustParseFIL("500"))
+
+	fees := ", max fee 0.000000000000025 FIL, nonce 1"
+	cases := []struct {
+		tx     string
+		action string
+		text   string
+	}{
+		{payment, rosettaFilecoinLib.OperationSend, "Send 1.5 FIL to " + To + fees},
+		{proposal, rosettaFilecoinLib.OperationPropose, "Propose on multisig " + Multisig + ": send 12.5 FIL to " + To + fees},
+		{addSigner, rosettaFilecoinLib.OperationAddSigner,
+			"Propose on multisig " + Multisig + ": add signer " + NewSigner + ", increase threshold: false" + fees},
+		{create, rosettaFilecoinLib.OperationCreateMultisig, "Create a multisig of signers " + Address + ", " + NewSigner +
+			" with threshold 2, funded with 100 FIL vesting over 0 epochs" + fees},
+		{approve, rosettaFilecoinLib.OperationApprove, "Approve transaction 3 of multisig " + Multisig + fees},
+		{string(call), rosettaFilecoinLib.SummaryCall, "Call method 100 of " + To + " with 1.5 FIL" + fees},
+		{valuedProposal, rosettaFilecoinLib.SummaryProposal, "Propose on multisig " + Multisig +
+			": Call method AddSigner (5) of " + Multisig + " with 0 FIL, sending 500 FIL" + fees},
+	}
+
+	for _, c := range cases {
+		summary, err := tool.SummarizeTx(c.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if summary.Action != c.action || summary.Text != c.text {
+			t.Errorf("unexpected %s summary: %s", summary.Action, summary.Text)
+		}
+		if summary.Text != summary.Render(rosettaFilecoinLib.SummaryTemplates) {
+			t.Error("the text should be rendered with the default templates")
+		}
+	}
+}
+
+func testMaxFee(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	maxFee, err := tool.MaxFee(base64.StdEncoding.EncodeToString(mustHex(t, unsignedTx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GasFeeCap 1 × GasLimit 25000
+	if !maxFee.Equals(abi.NewTokenAmount(25000)) {
+		t.Errorf("unexpected max fee %s", maxFee)
+	}
+
+	if _, err := tool.MaxFee("not a transaction"); err == nil {
+		t.Error("garbage input should fail")
+	}
+}
+
+func testConstructMethodCall(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	const minerAddress = "t01000"
+
+	withdraw, err := tool.ConstructMethodCall(&rosettaFilecoinLib.MethodCallRequest{
+		From:       Address,
+		To:         minerAddress,
+		Method:     uint64(builtin.MethodsMiner.WithdrawBalance),
+		Actor:      rosettaFilecoinLib.ActorMiner,
+		JSONParams: json.RawMessage(`{"AmountRequested":"1000"}`),
+		Metadata:   metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, withdraw)
+	checkHeader(t, msg, minerAddress)
+
+	var withdrawParams miner.WithdrawBalanceParams
+	if err := withdrawParams.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Method != builtin.MethodsMiner.WithdrawBalance || !withdrawParams.AmountRequested.Equals(abi.NewTokenAmount(1000)) {
+		t.Errorf("unexpected call %d %+v", msg.Method, withdrawParams)
+	}
+
+	// The market actor is recognized by its address
+	addBalance, err := tool.ConstructMethodCall(&rosettaFilecoinLib.MethodCallRequest{
+		From:       Address,
+		To:         builtin.StorageMarketActorAddr.String(),
+		Quantity:   abi.NewTokenAmount(500),
+		Method:     uint64(builtin.MethodsMarket.AddBalance),
+		JSONParams: json.RawMessage(`"` + Address + `"`),
+		Metadata:   metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := tool.ParseTxDetailed(addBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "AddBalance" || parsed.Params["provider_or_client"] != Address || !parsed.Value.Equals(abi.NewTokenAmount(500)) {
+		t.Errorf("unexpected AddBalance call %+v", parsed)
+	}
+
+	raw, err := tool.ConstructMethodCall(&rosettaFilecoinLib.MethodCallRequest{
+		From:     Address,
+		To:       minerAddress,
+		Method:   100,
+		Params:   []byte{0x80},
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := decodeUnsignedTx(t, raw); msg.Method != 100 || !bytes.Equal(msg.Params, []byte{0x80}) {
+		t.Errorf("unexpected raw call %d %x", msg.Method, msg.Params)
+	}
+
+	invalid := map[string]*rosettaFilecoinLib.MethodCallRequest{
+		"unknown field": {
+			To: minerAddress, Method: uint64(builtin.MethodsMiner.WithdrawBalance), Actor: rosettaFilecoinLib.ActorMiner,
+			JSONParams: json.RawMessage(`{"Amount":"1000"}`),
+		},
+		"missing actor": {
+			To: minerAddress, Method: uint64(builtin.MethodsMiner.WithdrawBalance),
+			JSONParams: json.RawMessage(`{"AmountRequested":"1000"}`),
+		},
+		"unknown method": {
+			To: minerAddress, Method: 100, Actor: rosettaFilecoinLib.ActorMiner,
+			JSONParams: json.RawMessage(`{}`),
+		},
+		"raw and JSON params": {
+			To: minerAddress, Method: uint64(builtin.MethodsMiner.WithdrawBalance), Actor: rosettaFilecoinLib.ActorMiner,
+			JSONParams: json.RawMessage(`{"AmountRequested":"1000"}`), Params: []byte{0x80},
+		},
+	}
+
+	for name, request := range invalid {
+		request.From = Address
+		request.Metadata = metadata()
+		if _, err := tool.ConstructMethodCall(request); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}
+
+func testConstructMiner(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	const (
+		minerAddress = "t01000"
+		peerID       = "12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf"
+	)
+
+	withdraw, err := tool.ConstructMinerWithdrawBalance(&rosettaFilecoinLib.MinerWithdrawBalanceRequest{
+		Miner:    minerAddress,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MinerWithdrawBalanceParams{AmountRequested: abi.NewTokenAmount(1000)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHeader(t, decodeUnsignedTx(t, withdraw), minerAddress)
+
+	parsed, err := tool.ParseTxDetailed(withdraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "WithdrawBalance" || parsed.Actor != rosettaFilecoinLib.ActorMiner || !abi.NewTokenAmount(1000).Equals(parsed.Params["amount_requested"].(abi.TokenAmount)) {
+		t.Errorf("unexpected WithdrawBalance call %+v", parsed)
+	}
+
+	// Owned by a multisig, the call is wrapped in a proposal
+	changeWorker, err := tool.ConstructMinerChangeWorkerAddress(&rosettaFilecoinLib.MinerChangeWorkerAddressRequest{
+		Miner:    minerAddress,
+		From:     Address,
+		Multisig: Multisig,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MinerChangeWorkerAddressParams{
+			NewWorker:       To,
+			NewControlAddrs: []string{NewSigner},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, changeWorker)
+	checkHeader(t, msg, Multisig)
+	if msg.Method != builtin.MethodsMultisig.Propose {
+		t.Errorf("unexpected method %d", msg.Method)
+	}
+
+	parsed, err = tool.ParseTxDetailed(changeWorker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposal, err := json.Marshal(parsed.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"proposal":{"to":"` + minerAddress + `","value":"0","method":3,"actor":"miner","method_name":"ChangeWorkerAddress",` +
+		`"params":{"new_control_addrs":["` + NewSigner + `"],"new_worker":"` + To + `"}}}`
+	if string(proposal) != expected {
+		t.Errorf("unexpected proposal %s", proposal)
+	}
+
+	checkSummary(t, tool, withdraw, "Withdraw 0.000000000000001 FIL from miner "+minerAddress)
+	checkSummary(t, tool, changeWorker, "Propose on multisig "+Multisig+": Change the worker of miner "+minerAddress+
+		" to "+To+", control addresses ["+NewSigner+"]")
+
+	// The value carried by the calls is shown besides their params
+	checkSummary(t, tool, withValue(t, withdraw, rosettaFilecoinLib.MustParseFIL("1000")),
+		"Withdraw 0.000000000000001 FIL from miner "+minerAddress+", sending 1000 FIL")
+	checkSummary(t, tool, withProposedValue(t, changeWorker, rosettaFilecoinLib.MustParseFIL("2")),
+		"Propose on multisig "+Multisig+": Change the worker of miner "+minerAddress+
+			" to "+To+", control addresses ["+NewSigner+"], sending 2 FIL")
+
+	changePeerID, err := tool.ConstructMinerChangePeerID(&rosettaFilecoinLib.MinerChangePeerIDRequest{
+		Miner:    minerAddress,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MinerChangePeerIDParams{NewID: peerID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err = tool.ParseTxDetailed(changePeerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "ChangePeerID" || parsed.Params["new_id"] != peerID {
+		t.Errorf("unexpected ChangePeerID call %+v", parsed)
+	}
+
+	checkSummary(t, tool, changePeerID, "Change the peer ID of miner "+minerAddress+" to "+peerID)
+
+	_, err = tool.ConstructMinerChangePeerID(&rosettaFilecoinLib.MinerChangePeerIDRequest{
+		Miner:    minerAddress,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MinerChangePeerIDParams{NewID: "not a peer id"},
+	})
+	if err == nil {
+		t.Error("invalid peer ID should be rejected")
+	}
+
+	_, err = tool.ConstructMinerWithdrawBalance(&rosettaFilecoinLib.MinerWithdrawBalanceRequest{
+		Miner:    minerAddress,
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MinerWithdrawBalanceParams{AmountRequested: abi.NewTokenAmount(0)},
+	})
+	if err == nil {
+		t.Error("zero withdrawal should be rejected")
+	}
+}
+
+func testConstructMarket(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	market := rosettaFilecoinLib.FormatAddress(builtin.StorageMarketActorAddr, false)
+
+	addBalance, err := tool.ConstructMarketAddBalance(&rosettaFilecoinLib.MarketAddBalanceRequest{
+		From:     Address,
+		Quantity: abi.NewTokenAmount(1000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, addBalance)
+	checkHeader(t, msg, market)
+	if msg.Method != builtin.MethodsMarket.AddBalance || !msg.Value.Equals(abi.NewTokenAmount(1000)) {
+		t.Errorf("unexpected AddBalance call %d %s", msg.Method, msg.Value)
+	}
+
+	// The escrow account defaults to the sender
+	parsed, err := tool.ParseTxDetailed(addBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "AddBalance" || parsed.Params["provider_or_client"] != Address {
+		t.Errorf("unexpected AddBalance call %+v", parsed)
+	}
+
+	withdraw, err := tool.ConstructMarketWithdrawBalance(&rosettaFilecoinLib.MarketWithdrawBalanceRequest{
+		From:     Address,
+		Metadata: metadata(),
+		Params: rosettaFilecoinLib.MarketWithdrawBalanceParams{
+			ProviderOrClient: To,
+			Amount:           abi.NewTokenAmount(500),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg = decodeUnsignedTx(t, withdraw)
+	checkHeader(t, msg, market)
+	if !msg.Value.IsZero() {
+		t.Errorf("WithdrawBalance should not carry value: %s", msg.Value)
+	}
+
+	parsed, err = tool.ParseTxDetailed(withdraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.MethodName != "WithdrawBalance" || parsed.Params["provider_or_client"] != To ||
+		!abi.NewTokenAmount(500).Equals(parsed.Params["amount"].(abi.TokenAmount)) {
+		t.Errorf("unexpected WithdrawBalance call %+v", parsed)
+	}
+
+	summary, err := tool.SummarizeTx(withdraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Action != rosettaFilecoinLib.OperationMarketWithdrawBalance || summary.Fields["provider_or_client"] != To {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	_, err = tool.ConstructMarketAddBalance(&rosettaFilecoinLib.MarketAddBalanceRequest{
+		From:     Address,
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: metadata(),
+	})
+	if err == nil {
+		t.Error("empty deposit should be rejected")
+	}
+
+	_, err = tool.ConstructMarketWithdrawBalance(&rosettaFilecoinLib.MarketWithdrawBalanceRequest{
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.MarketWithdrawBalanceParams{Amount: abi.NewTokenAmount(-1)},
+	})
+	if err == nil {
+		t.Error("negative withdrawal should be rejected")
+	}
+}
+
+func testConstructPaych(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	const channel = "t01005"
+
+	create, err := tool.ConstructPaychCreate(&rosettaFilecoinLib.PaychCreateRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(10000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := decodeUnsignedTx(t, create)
+	checkHeader(t, msg, rosettaFilecoinLib.FormatAddress(builtin.InitActorAddr, false))
+	if msg.Method != builtin.MethodsInit.Exec || !msg.Value.Equals(abi.NewTokenAmount(10000)) {
+		t.Errorf("unexpected creation %d %s", msg.Method, msg.Value)
+	}
+
+	parsed, err := tool.ParseTxDetailed(create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	constructor, err := json.Marshal(parsed.Params["constructor_params"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Params["code_cid"] != builtin.PaymentChannelActorCodeID.String() ||
+		string(constructor) != `{"from":"`+Address+`","to":"`+To+`"}` {
+		t.Errorf("unexpected creation %+v", parsed.Params)
+	}
+
+	checkSummary(t, tool, create, "Create a payment channel to "+To+" funded with 0.00000000000001 FIL")
+
+	secret := []byte("secret")
+	secretHash := blake2b.Sum256(secret)
+	voucher, err := tool.CreateVoucher(&rosettaFilecoinLib.VoucherRequest{
+		Channel:        channel,
+		Lane:           1,
+		Nonce:          2,
+		Amount:         abi.NewTokenAmount(500),
+		SecretPreimage: secretHash[:],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tool.ConstructPaychUpdateChannelState(&rosettaFilecoinLib.PaychUpdateChannelStateRequest{
+		From:     To,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.PaychUpdateChannelStateParams{Voucher: voucher, Secret: secret},
+	})
+	if err == nil {
+		t.Error("an unsigned voucher should be rejected")
+	}
+
+	signed, err := tool.SignVoucher(voucher, mustHex(t, SecretKey), crypto.SigTypeSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update, err := tool.ConstructPaychUpdateChannelState(&rosettaFilecoinLib.PaychUpdateChannelStateRequest{
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.PaychUpdateChannelStateParams{Voucher: signed, Secret: secret},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHeader(t, decodeUnsignedTx(t, update), channel)
+
+	parsed, err = tool.ParseTxDetailed(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := json.Marshal(parsed.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"secret":"` + hex.EncodeToString(secret) + `","voucher":{"amount":"500","channel":"` + channel + `",` +
+		`"lane":1,"min_settle_height":0,"nonce":2,"secret_preimage":"` + hex.EncodeToString(secretHash[:]) + `",` +
+		`"signed":true,"time_lock_max":0,"time_lock_min":0}}`
+	if parsed.Actor != rosettaFilecoinLib.ActorPaych || parsed.MethodName != "UpdateChannelState" || string(params) != expected {
+		t.Errorf("unexpected UpdateChannelState call %s %s", parsed.MethodName, params)
+	}
+
+	checkSummary(t, tool, update, "Redeem a voucher of 0.0000000000000005 FIL on lane 1 (nonce 2) of payment channel "+channel)
+	checkSummary(t, tool, withValue(t, update, rosettaFilecoinLib.MustParseFIL("10")),
+		"Redeem a voucher of 0.0000000000000005 FIL on lane 1 (nonce 2) of payment channel "+channel+", sending 10 FIL")
+
+	for name, construct := range map[string]func(*rosettaFilecoinLib.PaychRequest) (string, error){
+		"Settle":  tool.ConstructPaychSettle,
+		"Collect": tool.ConstructPaychCollect,
+	} {
+		tx, err := construct(&rosettaFilecoinLib.PaychRequest{Channel: channel, From: To, Metadata: metadata()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := tool.ParseTxDetailed(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.To != channel || parsed.MethodName != name || len(parsed.Params) != 0 || len(parsed.RawParams) != 0 {
+			t.Errorf("unexpected %s call %+v", name, parsed)
+		}
+
+		checkSummary(t, tool, tx, name+" payment channel "+channel)
+		checkSummary(t, tool, withValue(t, tx, rosettaFilecoinLib.MustParseFIL("10")),
+			name+" payment channel "+channel+", sending 10 FIL")
+	}
+}
+
+func testVoucher(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	voucher, err := tool.CreateVoucher(&rosettaFilecoinLib.VoucherRequest{
+		Channel:     "t01005",
+		Lane:        0,
+		Nonce:       1,
+		Amount:      abi.NewTokenAmount(100),
+		TimeLockMin: 10,
+		TimeLockMax: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tool.VerifyVoucher(voucher, Address); err == nil {
+		t.Error("an unsigned voucher should not verify")
+	}
+
+	signed, err := tool.SignVoucher(voucher, mustHex(t, SecretKey), crypto.SigTypeSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tool.VerifyVoucher(signed, Address); err != nil {
+		t.Errorf("voucher should verify: %v", err)
+	}
+	if err := tool.VerifyVoucher(signed, To); err == nil {
+		t.Error("voucher should not verify against another address")
+	}
+
+	// Vouchers are encoded like Lotus does
+	sv, err := types.DecodeSignedVoucher(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sv.Lane != 0 || sv.Nonce != 1 || sv.TimeLockMin != 10 || sv.TimeLockMax != 20 || sv.Signature == nil {
+		t.Errorf("unexpected voucher %+v", sv)
+	}
+
+	blsSigned, err := tool.SignVoucher(voucher, mustHex(t, BLSSecretKey), crypto.SigTypeBLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tool.VerifyVoucher(blsSigned, BLSAddress); err != nil {
+		t.Errorf("BLS voucher should verify: %v", err)
+	}
+
+	invalid := map[string]*rosettaFilecoinLib.VoucherRequest{
+		"zero nonce":         {Channel: "t01005", Amount: abi.NewTokenAmount(100)},
+		"negative amount":    {Channel: "t01005", Nonce: 1, Amount: abi.NewTokenAmount(-1)},
+		"inverted time lock": {Channel: "t01005", Nonce: 1, TimeLockMin: 20, TimeLockMax: 10},
+		"short secret hash":  {Channel: "t01005", Nonce: 1, SecretPreimage: []byte("secret")},
+	}
+	for name, request := range invalid {
+		if _, err := tool.CreateVoucher(request); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}
+
+func testConstructVerifreg(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	verifreg := rosettaFilecoinLib.FormatAddress(builtin.VerifiedRegistryActorAddr, false)
+	allowance := rosettaFilecoinLib.MustParseDataCap("1 TiB")
+
+	addClient, err := tool.ConstructVerifregAddVerifiedClient(&rosettaFilecoinLib.VerifregAllowanceRequest{
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.VerifregAllowanceParams{Address: To, Allowance: allowance},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHeader(t, decodeUnsignedTx(t, addClient), verifreg)
+	checkSummary(t, tool, addClient, "Grant 1 TiB of datacap to client "+To)
+	checkSummary(t, tool, withValue(t, addClient, rosettaFilecoinLib.MustParseFIL("3")),
+		"Grant 1 TiB of datacap to client "+To+", sending 3 FIL")
+
+	_, err = tool.ParseToOperations(addClient)
+	if err == nil || !strings.Contains(err.Error(), "unsupported method AddVerifiedClient") {
+		t.Errorf("verified registry calls should be unsupported operations: %v", err)
+	}
+
+	parsed, err := tool.ParseTxDetailed(addClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Actor != rosettaFilecoinLib.ActorVerifreg || parsed.MethodName != "AddVerifiedClient" ||
+		parsed.Params["address"] != To || !allowance.Equals(parsed.Params["allowance"].(abi.StoragePower)) {
+		t.Errorf("unexpected AddVerifiedClient call %+v", parsed)
+	}
+
+	// Root key holders propose on the root key multisig
+	addVerifier, err := tool.ConstructVerifregAddVerifier(&rosettaFilecoinLib.VerifregAllowanceRequest{
+		From:     Address,
+		Multisig: Multisig,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.VerifregAllowanceParams{Address: NewSigner, Allowance: allowance},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHeader(t, decodeUnsignedTx(t, addVerifier), Multisig)
+	checkSummary(t, tool, addVerifier, "Propose on multisig "+Multisig+": Add verifier "+NewSigner+" with an allowance of 1 TiB")
+
+	parsed, err = tool.ParseTxDetailed(addVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposal, err := json.Marshal(parsed.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"proposal":{"to":"` + verifreg + `","value":"0","method":2,"actor":"verifreg","method_name":"AddVerifier",` +
+		`"params":{"address":"` + NewSigner + `","allowance":"1099511627776"}}}`
+	if string(proposal) != expected {
+		t.Errorf("unexpected proposal %s", proposal)
+	}
+
+	removeVerifier, err := tool.ConstructVerifregRemoveVerifier(&rosettaFilecoinLib.VerifregRemoveVerifierRequest{
+		From:     Address,
+		Multisig: Multisig,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.VerifregRemoveVerifierParams{Address: NewSigner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkSummary(t, tool, removeVerifier, "Propose on multisig "+Multisig+": Remove verifier "+NewSigner)
+	checkSummary(t, tool, withProposedValue(t, removeVerifier, rosettaFilecoinLib.MustParseFIL("3")),
+		"Propose on multisig "+Multisig+": Remove verifier "+NewSigner+", sending 3 FIL")
+
+	parsed, err = tool.ParseTxDetailed(removeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call, ok := parsed.Params["proposal"].(*rosettaFilecoinLib.ParsedCall); !ok || call.MethodName != "RemoveVerifier" ||
+		call.Params["address"] != NewSigner {
+		t.Errorf("unexpected RemoveVerifier proposal %+v", parsed.Params)
+	}
+
+	_, err = tool.ConstructVerifregAddVerifiedClient(&rosettaFilecoinLib.VerifregAllowanceRequest{
+		From:     Address,
+		Metadata: metadata(),
+		Params:   rosettaFilecoinLib.VerifregAllowanceParams{Address: To, Allowance: rosettaFilecoinLib.MustParseDataCap("1 KiB")},
+	})
+	if err == nil {
+		t.Error("an allowance below the minimum verified deal size should be rejected")
+	}
+}
+
+func testHash(t *testing.T, tool rosettaFilecoinLib.RosettaConstructionTool) {
+	unsigned, err := tool.ConstructPayment(&rosettaFilecoinLib.PaymentRequest{
+		From:     Address,
+		To:       To,
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: metadata(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := tool.SignTx(unsigned, mustHex(t, SecretKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := tool.Hash(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash != decodeSignedTx(t, signed).Cid().String() {
+		t.Errorf("unexpected hash %s", hash)
+	}
+}
@@ -0,0 +1,243 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// EncodingFormat selects how transactions are serialized. Whatever the format, every transaction
+// returned by the library is base64 encoded, and every method accepts transactions in any format.
+type EncodingFormat int
+
+const (
+	// EncodingJSON serializes transactions as Lotus JSON (default)
+	EncodingJSON EncodingFormat = iota
+	// EncodingCBOR serializes transactions as CBOR, as they are stored on chain
+	EncodingCBOR
+)
+
+// jsonMessage is the Lotus JSON of a message with its addresses formatted for the configured network,
+// Lotus formats them for the network of the process which is always testnet in the library
+type jsonMessage struct {
+	Version    uint64
+	To         string
+	From       string
+	Nonce      uint64
+	Value      abi.TokenAmount
+	GasLimit   int64
+	GasFeeCap  abi.TokenAmount
+	GasPremium abi.TokenAmount
+	Method     abi.MethodNum
+	Params     []byte
+}
+
+// jsonSignedMessage is the Lotus JSON of a signed message, see jsonMessage
+type jsonSignedMessage struct {
+	Message   jsonMessage
+	Signature crypto.Signature
+}
+
+func (r RosettaConstructionFilecoin) toJSONMessage(msg *types.Message) jsonMessage {
+	return jsonMessage{
+		Version:    msg.Version,
+		To:         r.formatAddress(msg.To),
+		From:       r.formatAddress(msg.From),
+		Nonce:      msg.Nonce,
+		Value:      msg.Value,
+		GasLimit:   msg.GasLimit,
+		GasFeeCap:  msg.GasFeeCap,
+		GasPremium: msg.GasPremium,
+		Method:     msg.Method,
+		Params:     msg.Params,
+	}
+}
+
+// Number of fields of the CBOR arrays of messages and signed messages
+const (
+	cborMessageFields       = 10
+	cborSignedMessageFields = 2
+)
+
+func (r RosettaConstructionFilecoin) encodeMessage(msg *types.Message) (string, error) {
+	var tx []byte
+	var err error
+
+	switch r.Encoding {
+	case EncodingJSON:
+		tx, err = json.Marshal(r.toJSONMessage(msg))
+	case EncodingCBOR:
+		tx, err = msg.Serialize()
+	default:
+		err = fmt.Errorf("unknown encoding format %d", r.Encoding)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(tx), nil
+}
+
+func (r RosettaConstructionFilecoin) encodeSignedMessage(sm *types.SignedMessage) (string, error) {
+	var tx []byte
+	var err error
+
+	switch r.Encoding {
+	case EncodingJSON:
+		tx, err = json.Marshal(jsonSignedMessage{Message: r.toJSONMessage(&sm.Message), Signature: sm.Signature})
+	case EncodingCBOR:
+		tx, err = sm.Serialize()
+	default:
+		err = fmt.Errorf("unknown encoding format %d", r.Encoding)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(tx), nil
+}
+
+// DecodeTx decodes a transaction in any of the supported formats: base64 encoded JSON or CBOR,
+// or plain JSON. Exactly one of the returned message and signed message is set.
+func DecodeTx(tx string) (*types.Message, *types.SignedMessage, error) {
+	raw := []byte(tx)
+	if decoded, err := base64.StdEncoding.DecodeString(tx); err == nil {
+		raw = decoded
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return decodeJSONTx(trimmed)
+	}
+
+	return decodeCBORTx(raw)
+}
+
+func decodeJSONTx(raw []byte) (*types.Message, *types.SignedMessage, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, signed := fields["Signature"]; signed {
+		var sm types.SignedMessage
+		err = json.Unmarshal(raw, &sm)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = checkJSONAmounts(&sm.Message)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &sm, nil
+	}
+
+	var msg types.Message
+	err = json.Unmarshal(raw, &msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = checkJSONAmounts(&msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &msg, nil, nil
+}
+
+// checkJSONAmounts rejects a message whose JSON leaves out an amount, it would decode to a nil big integer
+func checkJSONAmounts(msg *types.Message) error {
+	amounts := []struct {
+		name   string
+		amount abi.TokenAmount
+	}{
+		{"Value", msg.Value},
+		{"GasFeeCap", msg.GasFeeCap},
+		{"GasPremium", msg.GasPremium},
+	}
+	for _, a := range amounts {
+		if a.amount.Int == nil {
+			return fmt.Errorf("transaction has no %s", a.name)
+		}
+	}
+	return nil
+}
+
+func decodeCBORTx(raw []byte) (*types.Message, *types.SignedMessage, error) {
+	br := cbg.GetPeeker(bytes.NewReader(raw))
+	scratch := make([]byte, 8)
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction is neither JSON nor CBOR: %v", err)
+	}
+
+	if maj != cbg.MajArray {
+		return nil, nil, fmt.Errorf("cbor input should be of type array")
+	}
+
+	switch extra {
+	case cborMessageFields:
+		msg, err := types.DecodeMessage(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		return msg, nil, nil
+	case cborSignedMessageFields:
+		sm, err := types.DecodeSignedMessage(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, sm, nil
+	default:
+		return nil, nil, fmt.Errorf("cbor input had wrong number of fields")
+	}
+}
+
+// decodeUnsignedTx decodes an unsigned transaction in any of the supported formats
+func decodeUnsignedTx(tx string) (*types.Message, error) {
+	msg, _, err := DecodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg == nil {
+		return nil, fmt.Errorf("expected an unsigned transaction")
+	}
+
+	return msg, nil
+}
+
+// decodeSignedTx decodes a signed transaction in any of the supported formats
+func decodeSignedTx(tx string) (*types.SignedMessage, error) {
+	_, sm, err := DecodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm == nil {
+		return nil, fmt.Errorf("expected a signed transaction")
+	}
+
+	return sm, nil
+}
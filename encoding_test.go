@@ -0,0 +1,201 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestRoundTripEncodings(t *testing.T) {
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	pr := &PaymentRequest{
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: TxMetadata{
+			Nonce:      1,
+			GasFeeCap:  abi.NewTokenAmount(1),
+			GasPremium: abi.NewTokenAmount(1),
+			GasLimit:   25000,
+		},
+	}
+
+	var hashes []string
+	for _, encoding := range []EncodingFormat{EncodingJSON, EncodingCBOR} {
+		r := &RosettaConstructionFilecoin{Mainnet: false, Encoding: encoding}
+
+		unsignedTx, err := r.ConstructPayment(pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := r.ParseTx(unsignedTx)
+		if err != nil {
+			t.Fatalf("encoding %d: ParseTx should accept ConstructPayment output: %v", encoding, err)
+		}
+
+		signedTx, err := r.SignTx(parsed, sk)
+		if err != nil {
+			t.Fatalf("encoding %d: SignTx should accept ParseTx output: %v", encoding, err)
+		}
+
+		parsedSigned, err := r.ParseTx(signedTx)
+		if err != nil {
+			t.Fatalf("encoding %d: ParseTx should accept SignTx output: %v", encoding, err)
+		}
+
+		if parsedSigned != signedTx {
+			t.Errorf("encoding %d: parsing a signed transaction should not alter it", encoding)
+		}
+
+		hash, err := r.Hash(parsedSigned)
+		if err != nil {
+			t.Fatalf("encoding %d: Hash should accept ParseTx output: %v", encoding, err)
+		}
+		hashes = append(hashes, hash)
+
+		if _, err := r.Hash(unsignedTx); err == nil {
+			t.Errorf("encoding %d: Hash should reject unsigned transactions", encoding)
+		}
+	}
+
+	if hashes[0] != hashes[1] {
+		t.Errorf("hash depends on the encoding: %s != %s", hashes[0], hashes[1])
+	}
+}
+
+func TestParseTxAutoDetection(t *testing.T) {
+	serializedTx := "8A005501FD1D0F4DFCD7E99AFCB99A8326B7DC459D32C6285501B882619D46558F3D9E316D11B48DCF211327025A0144000186A01961A84200014200010040"
+	cborTx, _ := hex.DecodeString(serializedTx)
+
+	jsonRosetta := &RosettaConstructionFilecoin{Mainnet: false}
+	cborRosetta := &RosettaConstructionFilecoin{Mainnet: false, Encoding: EncodingCBOR}
+
+	asJSON, err := jsonRosetta.ParseTx(base64.StdEncoding.EncodeToString(cborTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asCBOR, err := cborRosetta.ParseTx(asJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if asCBOR != base64.StdEncoding.EncodeToString(cborTx) {
+		t.Errorf("CBOR -> JSON -> CBOR should give back the input")
+	}
+
+	decodedJSON, _ := base64.StdEncoding.DecodeString(asJSON)
+	plain, err := cborRosetta.ParseTx(string(decodedJSON))
+	if err != nil {
+		t.Fatalf("plain JSON should be accepted: %v", err)
+	}
+
+	if plain != asCBOR {
+		t.Errorf("plain JSON should parse to the same transaction")
+	}
+
+	if _, err := jsonRosetta.ParseTx(base64.StdEncoding.EncodeToString([]byte{0x01, 0x02})); err == nil {
+		t.Error("garbage input should fail")
+	}
+}
+
+func TestMainnetEncoding(t *testing.T) {
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	r := &RosettaConstructionFilecoin{Mainnet: true}
+	pr := &PaymentRequest{
+		From:     "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		To:       "f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(100000),
+		Metadata: TxMetadata{
+			Nonce:      1,
+			GasFeeCap:  abi.NewTokenAmount(1),
+			GasPremium: abi.NewTokenAmount(1),
+			GasLimit:   25000,
+		},
+	}
+
+	unsignedTx, err := r.ConstructPayment(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := r.ParseTx(unsignedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedTx, err := r.SignTx(parsed, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedSigned, err := r.ParseTx(signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tx := range map[string]string{"unsigned": parsed, "signed": parsedSigned} {
+		decoded, err := base64.StdEncoding.DecodeString(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		json := string(decoded)
+		if !strings.Contains(json, `"To":"`+pr.To+`"`) || !strings.Contains(json, `"From":"`+pr.From+`"`) {
+			t.Errorf("%s transaction should have mainnet addresses: %s", name, json)
+		}
+	}
+
+	// The hash does not depend on the network prefix
+	testnet := &RosettaConstructionFilecoin{Mainnet: false}
+	mainnetHash, err := r.Hash(signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testnetHash, err := testnet.Hash(signedTx)
+	if err != nil || testnetHash != mainnetHash {
+		t.Errorf("unexpected hashes %s %s %v", mainnetHash, testnetHash, err)
+	}
+}
+
+func TestDecodeTxMissingAmounts(t *testing.T) {
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	noValue := `{"To":"t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"}`
+
+	if _, _, err := DecodeTx(noValue); err == nil || !strings.Contains(err.Error(), "Value") {
+		t.Errorf("a transaction without value should be rejected: %v", err)
+	}
+
+	if _, err := r.ParseToOperations(noValue); err == nil {
+		t.Error("ParseToOperations should reject a transaction without value")
+	}
+
+	if _, err := r.SummarizeTx(noValue); err == nil {
+		t.Error("SummarizeTx should reject a transaction without value")
+	}
+
+	noGas := `{"To":"t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",` +
+		`"Value":"1","GasPremium":"1"}`
+	if _, _, err := DecodeTx(noGas); err == nil || !strings.Contains(err.Error(), "GasFeeCap") {
+		t.Errorf("a transaction without gas fee cap should be rejected: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	fbig "github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+)
+
+// FeeGuard bounds the fees of the transactions signed by RosettaConstructionFilecoin, so that a mistyped
+// GasFeeCap or GasLimit cannot burn a fortune. Zero values disable the bounds.
+// The guard applies once the gas is known, when computing the signing payload and combining the signature:
+// construction stays possible without gas, e.g. to estimate it. The gas of the transactions signed must be set:
+// a positive GasLimit, GasFeeCap and GasPremium not negative.
+type FeeGuard struct {
+	// MaxFee is the highest max fee (GasFeeCap × GasLimit) allowed, in attoFIL
+	MaxFee abi.TokenAmount
+	// MaxFeePercent is the highest max fee allowed as a percentage of the value transferred (the value
+	// of the message, or of the proposal for a multisig Propose), e.g. 0.5 for 0.5%.
+	// Transactions transferring nothing are only bounded by MaxFee.
+	MaxFeePercent float64
+}
+
+// MaxFee returns the maximum fee msg can cost: GasFeeCap × GasLimit
+func MaxFee(msg *types.Message) abi.TokenAmount {
+	if msg.GasFeeCap.Nil() {
+		return fbig.Zero()
+	}
+	return fbig.Mul(msg.GasFeeCap, fbig.NewInt(msg.GasLimit))
+}
+
+func (r RosettaConstructionFilecoin) MaxFee(unsignedTx string) (abi.TokenAmount, error) {
+	msg, sm, err := DecodeTx(unsignedTx)
+	if err != nil {
+		return abi.TokenAmount{}, err
+	}
+
+	if sm != nil {
+		msg = &sm.Message
+	}
+
+	return MaxFee(msg), nil
+}
+
+// transferredValue returns the value transferred by msg, including the value of a multisig proposal
+func transferredValue(msg *types.Message) abi.TokenAmount {
+	value := msg.Value
+	if value.Nil() {
+		value = fbig.Zero()
+	}
+
+	if msg.Method == builtin.MethodsMultisig.Propose && msg.To != builtin.InitActorAddr {
+		var params multisig.ProposeParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err == nil && !params.Value.Nil() {
+			value = fbig.Add(value, params.Value)
+		}
+	}
+
+	return value
+}
+
+// checkFees enforces the fee guard, if any, on msg
+func (r RosettaConstructionFilecoin) checkFees(msg *types.Message) error {
+	if r.FeeGuard == nil {
+		return nil
+	}
+
+	// Nonsense gas would make the max fee zero or negative, passing the bounds
+	if msg.GasLimit <= 0 {
+		return fmt.Errorf("gas limit must be positive, got %d", msg.GasLimit)
+	}
+
+	if msg.GasFeeCap.Nil() || msg.GasFeeCap.Sign() < 0 {
+		return fmt.Errorf("gas fee cap must be set and not negative")
+	}
+
+	if msg.GasPremium.Nil() || msg.GasPremium.Sign() < 0 {
+		return fmt.Errorf("gas premium must be set and not negative")
+	}
+
+	if msg.GasPremium.GreaterThan(msg.GasFeeCap) {
+		return fmt.Errorf("gas premium %s exceeds the gas fee cap %s", msg.GasPremium, msg.GasFeeCap)
+	}
+
+	maxFee := MaxFee(msg)
+	if !r.FeeGuard.MaxFee.NilOrZero() && maxFee.GreaterThan(r.FeeGuard.MaxFee) {
+		return fmt.Errorf("max fee %s exceeds the limit of %s", FormatFIL(maxFee), FormatFIL(r.FeeGuard.MaxFee))
+	}
+
+	value := transferredValue(msg)
+	if r.FeeGuard.MaxFeePercent > 0 && !math.IsInf(r.FeeGuard.MaxFeePercent, 1) && value.Sign() > 0 {
+		// maxFee × 100 > value × MaxFeePercent, computed exactly with rationals: a float64 is a rational
+		fee := new(big.Rat).SetInt(fbig.Mul(maxFee, fbig.NewInt(100)).Int)
+		limit := new(big.Rat).Mul(new(big.Rat).SetInt(value.Int), new(big.Rat).SetFloat64(r.FeeGuard.MaxFeePercent))
+		if fee.Cmp(limit) > 0 {
+			return fmt.Errorf("max fee %s exceeds %g%% of the transferred %s", FormatFIL(maxFee),
+				r.FeeGuard.MaxFeePercent, FormatFIL(value))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func feeMetadata(gasFeeCap int64, gasPremium int64, gasLimit int64) TxMetadata {
+	return TxMetadata{
+		Nonce:      1,
+		GasFeeCap:  abi.NewTokenAmount(gasFeeCap),
+		GasPremium: abi.NewTokenAmount(gasPremium),
+		GasLimit:   gasLimit,
+	}
+}
+
+func TestMaxFee(t *testing.T) {
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	tx, err := r.ConstructPayment(&PaymentRequest{
+		From:     "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		To:       "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy",
+		Quantity: abi.NewTokenAmount(100),
+		Metadata: feeMetadata(1000, 10, 2500000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxFee, err := r.MaxFee(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !maxFee.Equals(abi.NewTokenAmount(2500000000)) {
+		t.Errorf("unexpected max fee %s", maxFee)
+	}
+}
+
+func TestFeeGuard(t *testing.T) {
+	from := "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+	to := "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy"
+
+	r := &RosettaConstructionFilecoin{
+		Mainnet:  false,
+		FeeGuard: &FeeGuard{MaxFee: MustParseFIL("0.01"), MaxFeePercent: 1},
+	}
+
+	payment := func(quantity string, metadata TxMetadata) error {
+		tx, err := r.ConstructPayment(&PaymentRequest{
+			From:     from,
+			To:       to,
+			Quantity: MustParseFIL(quantity),
+			Metadata: metadata,
+		})
+		if err != nil {
+			t.Fatalf("construction is not guarded: %v", err)
+		}
+		_, err = r.ConstructSigningPayload(tx)
+		return err
+	}
+
+	// A max fee of 0.005 FIL
+	if err := payment("1", feeMetadata(1000000000, 100000, 5000000)); err != nil {
+		t.Errorf("a fee below the limits should be accepted: %v", err)
+	}
+
+	// A max fee of 0.05 FIL
+	if err := payment("100", feeMetadata(10000000000, 100000, 5000000)); err == nil {
+		t.Error("a fee above the absolute limit should be rejected")
+	}
+
+	if err := payment("0.1", feeMetadata(1000000000, 100000, 5000000)); err == nil {
+		t.Error("a fee above 1% of the transferred value should be rejected")
+	}
+
+	if err := payment("0", feeMetadata(1000000000, 100000, 5000000)); err != nil {
+		t.Errorf("a transaction transferring nothing is only bounded by the absolute limit: %v", err)
+	}
+
+	if err := payment("1", feeMetadata(100, 101, 5000000)); err == nil {
+		t.Error("a gas premium above the fee cap should be rejected")
+	}
+
+	// A negative gas limit makes the max fee negative
+	if err := payment("1", feeMetadata(1000000000, 100000, -5000000)); err == nil {
+		t.Error("a negative gas limit should be rejected")
+	}
+
+	if err := payment("1", feeMetadata(1000000000, 100000, 0)); err == nil {
+		t.Error("a zero gas limit should be rejected")
+	}
+
+	// Signed transactions may come from elsewhere, with unset or negative gas prices
+	for name, msg := range map[string]*types.Message{
+		"unset gas fee cap":    {GasLimit: 5000000, GasPremium: abi.NewTokenAmount(1)},
+		"negative gas fee cap": {GasLimit: 5000000, GasFeeCap: abi.NewTokenAmount(-1), GasPremium: abi.NewTokenAmount(-2)},
+		"unset gas premium":    {GasLimit: 5000000, GasFeeCap: abi.NewTokenAmount(1)},
+		"negative gas premium": {GasLimit: 5000000, GasFeeCap: abi.NewTokenAmount(1), GasPremium: abi.NewTokenAmount(-1)},
+		"negative gas limit":   {GasLimit: -5000000, GasFeeCap: abi.NewTokenAmount(1), GasPremium: abi.NewTokenAmount(1)},
+	} {
+		if err := r.checkFees(msg); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+
+	// The percentage limit is exact, 1% of 2 billion FIL and 100 attoFIL is 20 million FIL and 1 attoFIL
+	bounded := &RosettaConstructionFilecoin{FeeGuard: &FeeGuard{MaxFeePercent: 1}}
+	large := &types.Message{
+		Value:      big.Add(MustParseFIL("2000000000"), abi.NewTokenAmount(100)),
+		GasLimit:   1,
+		GasFeeCap:  big.Add(MustParseFIL("20000000"), abi.NewTokenAmount(1)),
+		GasPremium: abi.NewTokenAmount(1),
+	}
+	if err := bounded.checkFees(large); err != nil {
+		t.Errorf("a max fee of exactly 1%% should be accepted: %v", err)
+	}
+	large.GasFeeCap = big.Add(large.GasFeeCap, abi.NewTokenAmount(1))
+	if err := bounded.checkFees(large); err == nil {
+		t.Error("a max fee 1 attoFIL above 1% should be rejected")
+	}
+
+	// The value of a multisig proposal is the value transferred
+	proposal, err := r.ConstructMultisigPayment(&MultisigPaymentRequest{
+		Multisig: "t01002",
+		From:     from,
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: feeMetadata(1000000000, 100000, 5000000),
+		Params:   MultisigPaymentParams{To: to, Quantity: MustParseFIL("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ConstructSigningPayload(proposal); err != nil {
+		t.Errorf("the proposal value should be taken into account: %v", err)
+	}
+
+	// Transactions built without the guard cannot be signed with it
+	unguarded := &RosettaConstructionFilecoin{Mainnet: false}
+	tx, err := unguarded.ConstructPayment(&PaymentRequest{
+		From:     from,
+		To:       to,
+		Quantity: MustParseFIL("100"),
+		Metadata: feeMetadata(10000000000, 100000, 5000000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sk, _ := hex.DecodeString("f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a")
+	if _, err := r.SignTx(tx, sk); err == nil {
+		t.Error("signing a transaction above the limits should fail")
+	}
+
+	payload, err := unguarded.ConstructSigningPayload(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := unguarded.SignWithType(payload.Bytes, sk, payload.SignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.CombineTx(tx, sig); err == nil {
+		t.Error("combining a transaction above the limits should fail")
+	}
+}
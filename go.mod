@@ -8,6 +8,9 @@ require (
 	github.com/filecoin-project/go-state-types v0.0.0-20200911004822-964d6c679cfc
 	github.com/filecoin-project/lotus v0.7.1
 	github.com/filecoin-project/specs-actors v0.9.10
+	github.com/ipfs/go-cid v0.0.7
+	github.com/kilic/bls12-381 v0.1.0
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
+	github.com/multiformats/go-multihash v0.0.14
 	github.com/whyrusleeping/cbor-gen v0.0.0-20200826160007-0b9f6c5fb163
 )
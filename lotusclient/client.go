@@ -0,0 +1,232 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+
+// Package lotusclient is a minimal typed client of the Lotus JSON-RPC API, covering what is needed
+// to submit the transactions built by rosettaFilecoinLib: nonces, gas estimation, push and receipts.
+// Signed transactions returned by SignTx are decoded with rosettaFilecoinLib.DecodeTx before MpoolPush.
+package lotusclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// DefaultTimeout bounds every call but StateWaitMsg, which waits as long as its context allows
+const DefaultTimeout = 60 * time.Second
+
+// Client calls the JSON-RPC API of a Lotus node
+type Client struct {
+	// URL of the RPC endpoint, e.g. http://127.0.0.1:1234/rpc/v0
+	URL string
+	// Token is the JWT sent as a bearer token, it may be empty for read-only calls
+	Token string
+	// Timeout bounds each call, zero disables it
+	Timeout time.Duration
+	// HTTPClient performs the requests
+	HTTPClient *http.Client
+
+	id uint64
+}
+
+// NewClient creates a client of the node at url using the given auth token
+func NewClient(url string, token string) *Client {
+	return &Client{
+		URL:        url,
+		Token:      token,
+		Timeout:    DefaultTimeout,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// RPCError is an error returned by the node
+type RPCError struct {
+	Method  string          `json:"-"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s failed with code %d: %s", e.Method, e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Call invokes method with params and decodes its result into result (which may be nil),
+// bounded by the client timeout
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	return c.call(ctx, method, result, params...)
+}
+
+func (c *Client) call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(&request{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Errors may come with a status other than 200, the RPC error is more useful than the status
+	var res response
+	err = json.Unmarshal(raw, &res)
+	if err == nil && res.Error != nil {
+		res.Error.Method = method
+		return res.Error
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed with HTTP status %s", method, resp.Status)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s returned an invalid response: %v", method, err)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(res.Result, result)
+}
+
+// MpoolGetNonce returns the next nonce of an account, taking pending messages into account
+func (c *Client) MpoolGetNonce(ctx context.Context, addr address.Address) (uint64, error) {
+	var nonce uint64
+	err := c.Call(ctx, "Filecoin.MpoolGetNonce", &nonce, addr)
+	return nonce, err
+}
+
+// GasEstimateMessageGas returns msg with its gas limit, fee cap and premium estimated
+func (c *Client) GasEstimateMessageGas(ctx context.Context, msg *types.Message) (*types.Message, error) {
+	var estimated types.Message
+	err := c.Call(ctx, "Filecoin.GasEstimateMessageGas", &estimated, msg, nil, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	return &estimated, nil
+}
+
+// MpoolPush submits a signed message and returns its cid
+func (c *Client) MpoolPush(ctx context.Context, sm *types.SignedMessage) (cid.Cid, error) {
+	var msgCid cid.Cid
+	err := c.Call(ctx, "Filecoin.MpoolPush", &msgCid, sm)
+	return msgCid, err
+}
+
+// MsgLookup is the result of StateWaitMsg
+type MsgLookup struct {
+	// Message can differ from the requested cid if the message was replaced with different gas values
+	Message cid.Cid
+	Receipt types.MessageReceipt
+	TipSet  types.TipSetKey
+	Height  abi.ChainEpoch
+}
+
+// StateWaitMsg blocks until the message is included on chain with the given confidence (in epochs)
+// and returns its receipt. It is only bounded by ctx, not by the client timeout.
+func (c *Client) StateWaitMsg(ctx context.Context, msg cid.Cid, confidence uint64) (*MsgLookup, error) {
+	var lookup MsgLookup
+	err := c.call(ctx, "Filecoin.StateWaitMsg", &lookup, msg, confidence)
+	if err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
+
+// StateLookupID returns the ID address of an account or actor, e.g. the requester of a multisig proposal
+func (c *Client) StateLookupID(ctx context.Context, addr address.Address) (address.Address, error) {
+	var id address.Address
+	err := c.Call(ctx, "Filecoin.StateLookupID", &id, addr, types.EmptyTSK)
+	return id, err
+}
+
+// ActorState is the result of StateReadState
+type ActorState struct {
+	Balance abi.TokenAmount
+	// State is the JSON representation of the actor state, its fields depend on the actor
+	State json.RawMessage
+}
+
+// StateReadState returns the balance and state of an actor at the head of the chain
+func (c *Client) StateReadState(ctx context.Context, actor address.Address) (*ActorState, error) {
+	var state ActorState
+	err := c.Call(ctx, "Filecoin.StateReadState", &state, actor, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
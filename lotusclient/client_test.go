@@ -0,0 +1,169 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package lotusclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const testAddress = "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+
+// rpcServer replies to each method with the given result or error
+func rpcServer(t *testing.T, token string, results map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch result := results[req.Method].(type) {
+		case *RPCError:
+			resp["error"] = result
+			w.WriteHeader(http.StatusInternalServerError)
+		case time.Duration:
+			<-time.After(result)
+			resp["result"] = nil
+		default:
+			resp["result"] = result
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestMpoolGetNonce(t *testing.T) {
+	srv := rpcServer(t, "token", map[string]interface{}{"Filecoin.MpoolGetNonce": 42})
+	defer srv.Close()
+
+	addr, _ := address.NewFromString(testAddress)
+	nonce, err := NewClient(srv.URL, "token").MpoolGetNonce(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nonce != 42 {
+		t.Errorf("unexpected nonce %d", nonce)
+	}
+}
+
+func TestStateLookupID(t *testing.T) {
+	srv := rpcServer(t, "token", map[string]interface{}{"Filecoin.StateLookupID": "t01234"})
+	defer srv.Close()
+
+	addr, _ := address.NewFromString(testAddress)
+	id, err := NewClient(srv.URL, "token").StateLookupID(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id.String() != "t01234" {
+		t.Errorf("unexpected ID address %s", id)
+	}
+}
+
+func TestMpoolPushAndWait(t *testing.T) {
+	addr, _ := address.NewFromString(testAddress)
+	sm := &types.SignedMessage{
+		Message: types.Message{
+			To:         addr,
+			From:       addr,
+			Value:      abi.NewTokenAmount(1),
+			GasFeeCap:  abi.NewTokenAmount(1),
+			GasPremium: abi.NewTokenAmount(1),
+		},
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{1}},
+	}
+
+	srv := rpcServer(t, "token", map[string]interface{}{
+		"Filecoin.MpoolPush": sm.Cid(),
+		"Filecoin.StateWaitMsg": map[string]interface{}{
+			"Message": sm.Cid(),
+			"Receipt": map[string]interface{}{"ExitCode": 16, "Return": nil, "GasUsed": 100},
+			"Height":  10,
+		},
+	})
+	defer srv.Close()
+
+	client := NewClient(srv.URL, "token")
+	msgCid, err := client.MpoolPush(context.Background(), sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgCid != sm.Cid() {
+		t.Errorf("unexpected cid %s", msgCid)
+	}
+
+	lookup, err := client.StateWaitMsg(context.Background(), msgCid, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lookup.Message != sm.Cid() || lookup.Receipt.ExitCode != 16 || lookup.Receipt.GasUsed != 100 || lookup.Height != 10 {
+		t.Errorf("unexpected lookup %+v", lookup)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := rpcServer(t, "token", map[string]interface{}{
+		"Filecoin.MpoolGetNonce": &RPCError{Code: 1, Message: "resolution lookup failed"},
+		"Filecoin.Slow":          time.Second,
+	})
+	defer srv.Close()
+
+	addr, _ := address.NewFromString(testAddress)
+
+	_, err := NewClient(srv.URL, "token").MpoolGetNonce(context.Background(), addr)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != 1 || rpcErr.Method != "Filecoin.MpoolGetNonce" {
+		t.Errorf("expected an RPC error, got %v", err)
+	}
+
+	_, err = NewClient(srv.URL, "wrong token").MpoolGetNonce(context.Background(), addr)
+	if err == nil || errors.As(err, &rpcErr) {
+		t.Errorf("expected an HTTP error, got %v", err)
+	}
+
+	client := NewClient(srv.URL, "token")
+	client.Timeout = 10 * time.Millisecond
+	if err := client.Call(context.Background(), "Filecoin.Slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+
+	msg := &types.Message{To: addr, From: addr}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.StateWaitMsg(ctx, msg.Cid(), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+}
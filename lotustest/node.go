@@ -0,0 +1,743 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+
+// Package lotustest provides an in-process stand-in for a Lotus node, serving the subset of the
+// JSON-RPC API used by lotusclient, so the construct, sign, push and wait flow can be tested offline.
+//
+// The node keeps a nonce per account, verifies the signature of pushed messages, executes them
+// right away against a simulated state (balances and multisigs) and records synthetic receipts.
+// It is not a Filecoin implementation: gas is not charged and only a few actor methods are simulated.
+package lotustest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/ipfs/go-cid"
+	"github.com/minio/blake2b-simd"
+
+	"github.com/zondax/rosetta-filecoin-lib/lotusclient"
+)
+
+// Gas values returned by GasEstimateMessageGas
+const (
+	GasLimit = 2180810
+)
+
+var (
+	GasFeeCap  = abi.NewTokenAmount(149794)
+	GasPremium = abi.NewTokenAmount(149470)
+)
+
+// Verifier checks the signature of a message before it is accepted in the mpool,
+// e.g. with the verification of rosettaFilecoinLib
+type Verifier func(sm *types.SignedMessage) error
+
+// Multisig is the simulated state of a multisig actor
+type Multisig struct {
+	Signers               []address.Address
+	NumApprovalsThreshold uint64
+	NextTxnID             multisig.TxnID
+	InitialBalance        abi.TokenAmount
+	StartEpoch            abi.ChainEpoch
+	UnlockDuration        abi.ChainEpoch
+
+	// Pending are the transactions waiting for approvals, not part of the JSON state
+	Pending map[multisig.TxnID]*multisig.Transaction `json:"-"`
+}
+
+// Node is a fake Lotus node serving JSON-RPC over HTTP
+type Node struct {
+	// URL of the JSON-RPC endpoint
+	URL string
+
+	verify Verifier
+	server *httptest.Server
+
+	mu        sync.Mutex
+	height    abi.ChainEpoch
+	nonces    map[address.Address]uint64
+	balances  map[address.Address]abi.TokenAmount
+	ids       map[address.Address]address.Address
+	multisigs map[address.Address]*Multisig
+	lookups   map[cid.Cid]*lotusclient.MsgLookup
+	nextID    uint64
+}
+
+// firstActorID is the ID given to the first actor created by the node
+const firstActorID = 1000
+
+// NewNode starts a node verifying pushed messages with verify. Close must be called to stop it.
+func NewNode(verify Verifier) *Node {
+	n := &Node{
+		verify:    verify,
+		nonces:    map[address.Address]uint64{},
+		balances:  map[address.Address]abi.TokenAmount{},
+		ids:       map[address.Address]address.Address{},
+		multisigs: map[address.Address]*Multisig{},
+		lookups:   map[cid.Cid]*lotusclient.MsgLookup{},
+		nextID:    firstActorID,
+	}
+
+	n.server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	n.URL = n.server.URL
+	return n
+}
+
+// Close stops the node
+func (n *Node) Close() {
+	n.server.Close()
+}
+
+// SetID registers the ID address of a key address, multisig signers are resolved to their ID
+func (n *Node) SetID(key address.Address, id address.Address) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.ids[key] = id
+}
+
+// SetBalance sets the balance of an account
+func (n *Node) SetBalance(addr address.Address, balance abi.TokenAmount) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.balances[n.resolve(addr)] = balance
+}
+
+// Balance returns the balance of an account
+func (n *Node) Balance(addr address.Address) abi.TokenAmount {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.balance(n.resolve(addr))
+}
+
+// AddMultisig creates a multisig actor at addr with the given signers, approval threshold and balance
+func (n *Node) AddMultisig(addr address.Address, signers []address.Address, threshold uint64, balance abi.TokenAmount) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	resolved := make([]address.Address, len(signers))
+	for i, signer := range signers {
+		resolved[i] = n.resolve(signer)
+	}
+
+	n.multisigs[addr] = &Multisig{
+		Signers:               resolved,
+		NumApprovalsThreshold: threshold,
+		InitialBalance:        big.Zero(),
+		Pending:               map[multisig.TxnID]*multisig.Transaction{},
+	}
+	n.balances[addr] = balance
+}
+
+// Multisig returns a copy of the state of a multisig, nil if there is no multisig at addr
+func (n *Node) Multisig(addr address.Address) *Multisig {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	msig, ok := n.multisigs[addr]
+	if !ok {
+		return nil
+	}
+
+	state := *msig
+	state.Signers = append([]address.Address{}, msig.Signers...)
+	state.Pending = make(map[multisig.TxnID]*multisig.Transaction, len(msig.Pending))
+	for id, txn := range msig.Pending {
+		state.Pending[id] = txn
+	}
+	return &state
+}
+
+func (n *Node) resolve(addr address.Address) address.Address {
+	if id, ok := n.ids[addr]; ok {
+		return id
+	}
+	return addr
+}
+
+func (n *Node) balance(addr address.Address) abi.TokenAmount {
+	if balance, ok := n.balances[addr]; ok {
+		return balance
+	}
+	return big.Zero()
+}
+
+type rpcRequest struct {
+	ID     uint64            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+
+	result, err := n.call(req.Method, req.Params)
+	if err != nil {
+		resp["error"] = &rpcError{Code: 1, Message: err.Error()}
+	} else {
+		resp["result"] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// params decodes the positional params of a call into out, extra params are ignored
+func params(raw []json.RawMessage, out ...interface{}) error {
+	if len(raw) < len(out) {
+		return fmt.Errorf("expected %d params, got %d", len(out), len(raw))
+	}
+
+	for i := range out {
+		if err := json.Unmarshal(raw[i], out[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *Node) call(method string, raw []json.RawMessage) (interface{}, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch method {
+	case "Filecoin.MpoolGetNonce":
+		var addr address.Address
+		if err := params(raw, &addr); err != nil {
+			return nil, err
+		}
+		return n.nonces[addr], nil
+
+	case "Filecoin.GasEstimateMessageGas":
+		var msg types.Message
+		if err := params(raw, &msg); err != nil {
+			return nil, err
+		}
+		msg.GasLimit = GasLimit
+		msg.GasFeeCap = GasFeeCap
+		msg.GasPremium = GasPremium
+		return &msg, nil
+
+	case "Filecoin.MpoolPush":
+		var sm types.SignedMessage
+		if err := params(raw, &sm); err != nil {
+			return nil, err
+		}
+		return n.push(&sm)
+
+	case "Filecoin.StateWaitMsg":
+		var msgCid cid.Cid
+		if err := params(raw, &msgCid); err != nil {
+			return nil, err
+		}
+
+		lookup, ok := n.lookups[msgCid]
+		if !ok {
+			return nil, fmt.Errorf("message %s not found", msgCid)
+		}
+		return lookup, nil
+
+	case "Filecoin.StateLookupID":
+		var addr address.Address
+		if err := params(raw, &addr); err != nil {
+			return nil, err
+		}
+
+		id := n.resolve(addr)
+		if id.Protocol() != address.ID {
+			return nil, fmt.Errorf("actor %s not found", addr)
+		}
+		return id, nil
+
+	case "Filecoin.StateReadState":
+		var addr address.Address
+		if err := params(raw, &addr); err != nil {
+			return nil, err
+		}
+
+		state := &lotusclient.ActorState{Balance: n.balance(addr), State: json.RawMessage("{}")}
+		if msig, ok := n.multisigs[addr]; ok {
+			var err error
+			state.State, err = json.Marshal(msig)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return state, nil
+
+	default:
+		return nil, fmt.Errorf("method %s not supported by the fake node", method)
+	}
+}
+
+// push accepts a message in the mpool and executes it right away
+func (n *Node) push(sm *types.SignedMessage) (cid.Cid, error) {
+	msg := &sm.Message
+
+	if n.verify == nil {
+		return cid.Undef, fmt.Errorf("no signature verifier")
+	}
+
+	if err := n.verify(sm); err != nil {
+		return cid.Undef, fmt.Errorf("invalid signature: %v", err)
+	}
+
+	if expected := n.nonces[msg.From]; msg.Nonce != expected {
+		return cid.Undef, fmt.Errorf("invalid nonce %d, expected %d", msg.Nonce, expected)
+	}
+
+	n.nonces[msg.From]++
+	n.height++
+
+	code, ret := n.execute(msg)
+	n.lookups[sm.Cid()] = &lotusclient.MsgLookup{
+		Message: sm.Cid(),
+		Receipt: types.MessageReceipt{
+			ExitCode: code,
+			Return:   ret,
+			GasUsed:  msg.GasLimit,
+		},
+		TipSet: types.EmptyTSK,
+		Height: n.height,
+	}
+
+	return sm.Cid(), nil
+}
+
+// execute applies a message to the state and returns its exit code and return value
+func (n *Node) execute(msg *types.Message) (exitcode.ExitCode, []byte) {
+	from := n.resolve(msg.From)
+	to := n.resolve(msg.To)
+
+	// As on chain, the value is available to the method and the transfer is reverted when the method fails
+	balances := make(map[address.Address]abi.TokenAmount, len(n.balances))
+	for addr, balance := range n.balances {
+		balances[addr] = balance
+	}
+
+	if code := n.transfer(from, to, msg.Value); code != exitcode.Ok {
+		return code, nil
+	}
+
+	code, ret := n.dispatch(msg, from, to)
+	if code != exitcode.Ok {
+		n.balances = balances
+	}
+	return code, ret
+}
+
+// dispatch runs the method of msg, from and to are the resolved addresses of the message
+func (n *Node) dispatch(msg *types.Message, from address.Address, to address.Address) (exitcode.ExitCode, []byte) {
+	msig, isMultisig := n.multisigs[to]
+
+	switch {
+	case msg.Method == builtin.MethodSend:
+		return exitcode.Ok, nil
+
+	case to == builtin.InitActorAddr && msg.Method == builtin.MethodsInit.Exec:
+		var params init_.ExecParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			return exitcode.ErrSerialization, nil
+		}
+		return n.exec(msg, &params)
+
+	case isMultisig && msg.Method == builtin.MethodsMultisig.Propose:
+		var params multisig.ProposeParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			return exitcode.ErrSerialization, nil
+		}
+		return n.propose(to, msig, from, &params)
+
+	case isMultisig && (msg.Method == builtin.MethodsMultisig.Approve || msg.Method == builtin.MethodsMultisig.Cancel):
+		var params multisig.TxnIDParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			return exitcode.ErrSerialization, nil
+		}
+
+		if msg.Method == builtin.MethodsMultisig.Approve {
+			return n.approve(to, msig, from, &params)
+		}
+		return n.cancel(msig, from, &params), nil
+
+	default:
+		return exitcode.SysErrInvalidMethod, nil
+	}
+}
+
+func (n *Node) transfer(from address.Address, to address.Address, value abi.TokenAmount) exitcode.ExitCode {
+	if value.NilOrZero() {
+		return exitcode.Ok
+	}
+
+	balance := n.balance(from)
+	if balance.LessThan(value) {
+		return exitcode.SysErrInsufficientFunds
+	}
+
+	n.balances[from] = big.Sub(balance, value)
+	n.balances[to] = big.Add(n.balance(to), value)
+	return exitcode.Ok
+}
+
+// exec creates an actor, only multisigs are supported. The value of the message was sent to the init actor,
+// it becomes the balance of the new actor.
+func (n *Node) exec(msg *types.Message, params *init_.ExecParams) (exitcode.ExitCode, []byte) {
+	if params.CodeCID != builtin.MultisigActorCodeID {
+		return exitcode.ErrForbidden, nil
+	}
+
+	var constructorParams multisig.ConstructorParams
+	if err := constructorParams.UnmarshalCBOR(bytes.NewReader(params.ConstructorParams)); err != nil {
+		return exitcode.ErrSerialization, nil
+	}
+
+	if len(constructorParams.Signers) == 0 || constructorParams.NumApprovalsThreshold == 0 ||
+		constructorParams.NumApprovalsThreshold > uint64(len(constructorParams.Signers)) {
+		return exitcode.ErrIllegalArgument, nil
+	}
+
+	idAddress, err := address.NewIDAddress(n.nextID)
+	if err != nil {
+		return exitcode.ErrIllegalState, nil
+	}
+	n.nextID++
+
+	robustAddress, err := actorAddress(msg.From, msg.Nonce)
+	if err != nil {
+		return exitcode.ErrIllegalState, nil
+	}
+	n.ids[robustAddress] = idAddress
+
+	signers := make([]address.Address, len(constructorParams.Signers))
+	for i, signer := range constructorParams.Signers {
+		signers[i] = n.resolve(signer)
+	}
+
+	n.multisigs[idAddress] = &Multisig{
+		Signers:               signers,
+		NumApprovalsThreshold: constructorParams.NumApprovalsThreshold,
+		InitialBalance:        msg.Value,
+		StartEpoch:            n.height,
+		UnlockDuration:        constructorParams.UnlockDuration,
+		Pending:               map[multisig.TxnID]*multisig.Transaction{},
+	}
+	n.transfer(builtin.InitActorAddr, idAddress, msg.Value)
+
+	buf := new(bytes.Buffer)
+	result := &init_.ExecReturn{IDAddress: idAddress, RobustAddress: robustAddress}
+	if err := result.MarshalCBOR(buf); err != nil {
+		return exitcode.ErrSerialization, nil
+	}
+
+	return exitcode.Ok, buf.Bytes()
+}
+
+func (n *Node) propose(addr address.Address, msig *Multisig, proposer address.Address, params *multisig.ProposeParams) (exitcode.ExitCode, []byte) {
+	if !isSigner(msig, proposer) {
+		return exitcode.ErrForbidden, nil
+	}
+
+	txn := &multisig.Transaction{
+		To:       params.To,
+		Value:    params.Value,
+		Method:   params.Method,
+		Params:   params.Params,
+		Approved: []address.Address{proposer},
+	}
+
+	thresholdMet := uint64(len(txn.Approved)) >= msig.NumApprovalsThreshold
+	if thresholdMet && !n.available(addr, msig, txn.Value) {
+		return exitcode.ErrInsufficientFunds, nil
+	}
+
+	txnID := msig.NextTxnID
+	msig.NextTxnID++
+
+	result := multisig.ProposeReturn{TxnID: txnID}
+	if thresholdMet {
+		result.Applied = true
+		result.Code = n.apply(addr, msig, txn)
+	} else {
+		msig.Pending[txnID] = txn
+	}
+
+	buf := new(bytes.Buffer)
+	if err := result.MarshalCBOR(buf); err != nil {
+		return exitcode.ErrSerialization, nil
+	}
+
+	return exitcode.Ok, buf.Bytes()
+}
+
+// pending returns a pending transaction, checking its proposal hash if one is given
+func pending(msig *Multisig, params *multisig.TxnIDParams) (*multisig.Transaction, exitcode.ExitCode) {
+	txn, ok := msig.Pending[params.ID]
+	if !ok {
+		return nil, exitcode.ErrNotFound
+	}
+
+	if len(params.ProposalHash) != 0 {
+		hash, err := multisig.ComputeProposalHash(txn, blake2b.Sum256)
+		if err != nil {
+			return nil, exitcode.ErrIllegalState
+		}
+		if !bytes.Equal(hash, params.ProposalHash) {
+			return nil, exitcode.ErrIllegalArgument
+		}
+	}
+
+	return txn, exitcode.Ok
+}
+
+func (n *Node) approve(addr address.Address, msig *Multisig, approver address.Address, params *multisig.TxnIDParams) (exitcode.ExitCode, []byte) {
+	if !isSigner(msig, approver) {
+		return exitcode.ErrForbidden, nil
+	}
+
+	txn, code := pending(msig, params)
+	if code != exitcode.Ok {
+		return code, nil
+	}
+
+	for _, approved := range txn.Approved {
+		if approved == approver {
+			return exitcode.ErrForbidden, nil
+		}
+	}
+
+	thresholdMet := uint64(len(txn.Approved)+1) >= msig.NumApprovalsThreshold
+	if thresholdMet && !n.available(addr, msig, txn.Value) {
+		return exitcode.ErrInsufficientFunds, nil
+	}
+
+	txn.Approved = append(txn.Approved, approver)
+
+	var result multisig.ApproveReturn
+	if thresholdMet {
+		delete(msig.Pending, params.ID)
+		result.Applied = true
+		result.Code = n.apply(addr, msig, txn)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := result.MarshalCBOR(buf); err != nil {
+		return exitcode.ErrSerialization, nil
+	}
+
+	return exitcode.Ok, buf.Bytes()
+}
+
+func (n *Node) cancel(msig *Multisig, proposer address.Address, params *multisig.TxnIDParams) exitcode.ExitCode {
+	if !isSigner(msig, proposer) {
+		return exitcode.ErrForbidden
+	}
+
+	txn, code := pending(msig, params)
+	if code != exitcode.Ok {
+		return code
+	}
+
+	if txn.Approved[0] != proposer {
+		return exitcode.ErrForbidden
+	}
+
+	delete(msig.Pending, params.ID)
+	return exitcode.Ok
+}
+
+// available checks that value can be spent by the multisig at the current height, the actor aborts otherwise
+func (n *Node) available(addr address.Address, msig *Multisig, value abi.TokenAmount) bool {
+	if value.NilOrZero() {
+		return true
+	}
+
+	balance := n.balance(addr)
+	if balance.LessThan(value) {
+		return false
+	}
+
+	st := multisig.State{
+		InitialBalance: msig.InitialBalance,
+		StartEpoch:     msig.StartEpoch,
+		UnlockDuration: msig.UnlockDuration,
+	}
+	return !big.Sub(balance, value).LessThan(st.AmountLocked(n.height - msig.StartEpoch))
+}
+
+// apply executes an approved multisig transaction
+func (n *Node) apply(addr address.Address, msig *Multisig, txn *multisig.Transaction) exitcode.ExitCode {
+	to := n.resolve(txn.To)
+
+	if code := n.transfer(addr, to, txn.Value); code != exitcode.Ok {
+		return code
+	}
+
+	if txn.Method == builtin.MethodSend {
+		return exitcode.Ok
+	}
+
+	if to != addr {
+		return exitcode.SysErrInvalidMethod
+	}
+
+	switch txn.Method {
+	case builtin.MethodsMultisig.SwapSigner:
+		var params multisig.SwapSignerParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(txn.Params)); err != nil {
+			return exitcode.ErrSerialization
+		}
+
+		from, to := n.resolve(params.From), n.resolve(params.To)
+		if !isSigner(msig, from) || isSigner(msig, to) {
+			return exitcode.ErrIllegalArgument
+		}
+
+		for i, signer := range msig.Signers {
+			if signer == from {
+				msig.Signers[i] = to
+			}
+		}
+		return exitcode.Ok
+
+	case builtin.MethodsMultisig.AddSigner:
+		var params multisig.AddSignerParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(txn.Params)); err != nil {
+			return exitcode.ErrSerialization
+		}
+
+		signer := n.resolve(params.Signer)
+		if isSigner(msig, signer) {
+			return exitcode.ErrForbidden
+		}
+
+		msig.Signers = append(msig.Signers, signer)
+		if params.Increase {
+			msig.NumApprovalsThreshold++
+		}
+		return exitcode.Ok
+
+	case builtin.MethodsMultisig.RemoveSigner:
+		var params multisig.RemoveSignerParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(txn.Params)); err != nil {
+			return exitcode.ErrSerialization
+		}
+
+		signer := n.resolve(params.Signer)
+		if !isSigner(msig, signer) || len(msig.Signers) == 1 {
+			return exitcode.ErrForbidden
+		}
+
+		if !params.Decrease && uint64(len(msig.Signers)-1) < msig.NumApprovalsThreshold {
+			return exitcode.ErrIllegalArgument
+		}
+
+		signers := make([]address.Address, 0, len(msig.Signers)-1)
+		for _, s := range msig.Signers {
+			if s != signer {
+				signers = append(signers, s)
+			}
+		}
+		msig.Signers = signers
+		if params.Decrease {
+			msig.NumApprovalsThreshold--
+		}
+		return exitcode.Ok
+
+	case builtin.MethodsMultisig.ChangeNumApprovalsThreshold:
+		var params multisig.ChangeNumApprovalsThresholdParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(txn.Params)); err != nil {
+			return exitcode.ErrSerialization
+		}
+
+		if params.NewThreshold == 0 || params.NewThreshold > uint64(len(msig.Signers)) {
+			return exitcode.ErrIllegalArgument
+		}
+
+		msig.NumApprovalsThreshold = params.NewThreshold
+		return exitcode.Ok
+
+	case builtin.MethodsMultisig.LockBalance:
+		var params multisig.LockBalanceParams
+		if err := params.UnmarshalCBOR(bytes.NewReader(txn.Params)); err != nil {
+			return exitcode.ErrSerialization
+		}
+
+		if params.UnlockDuration <= 0 {
+			return exitcode.ErrIllegalArgument
+		}
+		if msig.UnlockDuration != 0 {
+			return exitcode.ErrForbidden
+		}
+
+		msig.InitialBalance = params.Amount
+		msig.StartEpoch = params.StartEpoch
+		msig.UnlockDuration = params.UnlockDuration
+		return exitcode.Ok
+
+	default:
+		return exitcode.SysErrInvalidMethod
+	}
+}
+
+func isSigner(msig *Multisig, addr address.Address) bool {
+	for _, signer := range msig.Signers {
+		if signer == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// actorAddress returns the robust address of the actor created by the message of from with nonce,
+// seeded like Lotus with the creator address and the full big endian nonce
+func actorAddress(from address.Address, nonce uint64) (address.Address, error) {
+	seed := new(bytes.Buffer)
+	if err := from.MarshalCBOR(seed); err != nil {
+		return address.Undef, err
+	}
+	if err := binary.Write(seed, binary.BigEndian, nonce); err != nil {
+		return address.Undef, err
+	}
+	return address.NewActorAddress(seed.Bytes())
+}
@@ -0,0 +1,437 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package lotustest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/minio/blake2b-simd"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/zondax/rosetta-filecoin-lib/lotusclient"
+)
+
+// validSignature accepts any signature but the empty one
+var validSignature = []byte{1}
+
+func verifyNonEmpty(sm *types.SignedMessage) error {
+	if len(sm.Signature.Data) == 0 {
+		return fmt.Errorf("empty signature")
+	}
+	return nil
+}
+
+func mustAddress(t *testing.T, s string) address.Address {
+	addr, err := address.NewFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+func signed(msg types.Message) *types.SignedMessage {
+	return &types.SignedMessage{
+		Message:   msg,
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: validSignature},
+	}
+}
+
+func proposal(t *testing.T, from address.Address, msig address.Address, nonce uint64, params *multisig.ProposeParams) *types.SignedMessage {
+	buf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return signed(types.Message{
+		From:   from,
+		To:     msig,
+		Nonce:  nonce,
+		Value:  abi.NewTokenAmount(0),
+		Method: builtin.MethodsMultisig.Propose,
+		Params: buf.Bytes(),
+	})
+}
+
+func txnIDCall(t *testing.T, from address.Address, msig address.Address, nonce uint64, method abi.MethodNum, params *multisig.TxnIDParams) *types.SignedMessage {
+	buf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return signed(types.Message{
+		From:   from,
+		To:     msig,
+		Nonce:  nonce,
+		Value:  abi.NewTokenAmount(0),
+		Method: method,
+		Params: buf.Bytes(),
+	})
+}
+
+// selfProposal proposes a call of the multisig to itself and returns its exit code, the multisig must apply it
+func selfProposal(t *testing.T, client *lotusclient.Client, from address.Address, msig address.Address, nonce uint64,
+	method abi.MethodNum, params cbg.CBORMarshaler) exitcode.ExitCode {
+	buf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := pushAndWait(t, client, proposal(t, from, msig, nonce, &multisig.ProposeParams{
+		To:     msig,
+		Value:  abi.NewTokenAmount(0),
+		Method: method,
+		Params: buf.Bytes(),
+	}))
+
+	var ret multisig.ProposeReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(lookup.Receipt.Return)); err != nil {
+		t.Fatal(err)
+	}
+	return ret.Code
+}
+
+func pushAndWait(t *testing.T, client *lotusclient.Client, sm *types.SignedMessage) *lotusclient.MsgLookup {
+	msgCid, err := client.MpoolPush(context.Background(), sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup, err := client.StateWaitMsg(context.Background(), msgCid, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lookup
+}
+
+func TestSendAndNonces(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	from := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	to := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	node.SetBalance(from, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+	ctx := context.Background()
+
+	nonce, err := client.MpoolGetNonce(ctx, from)
+	if err != nil || nonce != 0 {
+		t.Fatalf("unexpected nonce %d: %v", nonce, err)
+	}
+
+	msg := types.Message{From: from, To: to, Nonce: 0, Value: abi.NewTokenAmount(400)}
+
+	unsigned := signed(msg)
+	unsigned.Signature.Data = nil
+	if _, err := client.MpoolPush(ctx, unsigned); err == nil {
+		t.Error("a message with an invalid signature should be rejected")
+	}
+
+	lookup := pushAndWait(t, client, signed(msg))
+	if lookup.Receipt.ExitCode != exitcode.Ok {
+		t.Errorf("unexpected exit code %d", lookup.Receipt.ExitCode)
+	}
+
+	if _, err := client.MpoolPush(ctx, signed(msg)); err == nil {
+		t.Error("a reused nonce should be rejected")
+	}
+
+	nonce, err = client.MpoolGetNonce(ctx, from)
+	if err != nil || nonce != 1 {
+		t.Fatalf("unexpected nonce %d: %v", nonce, err)
+	}
+
+	if !node.Balance(from).Equals(abi.NewTokenAmount(600)) || !node.Balance(to).Equals(abi.NewTokenAmount(400)) {
+		t.Errorf("unexpected balances %s %s", node.Balance(from), node.Balance(to))
+	}
+
+	msg.Nonce = 1
+	msg.Value = abi.NewTokenAmount(1000)
+	lookup = pushAndWait(t, client, signed(msg))
+	if lookup.Receipt.ExitCode != exitcode.SysErrInsufficientFunds {
+		t.Errorf("unexpected exit code %d", lookup.Receipt.ExitCode)
+	}
+
+	// The value of a failed call stays with the sender
+	msg.Nonce = 2
+	msg.Value = abi.NewTokenAmount(100)
+	msg.Method = 42
+	lookup = pushAndWait(t, client, signed(msg))
+	if lookup.Receipt.ExitCode != exitcode.SysErrInvalidMethod {
+		t.Errorf("unexpected exit code %d", lookup.Receipt.ExitCode)
+	}
+
+	if !node.Balance(from).Equals(abi.NewTokenAmount(600)) || !node.Balance(to).Equals(abi.NewTokenAmount(400)) {
+		t.Errorf("failed call should not move value: %s %s", node.Balance(from), node.Balance(to))
+	}
+}
+
+func TestMultisig(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	signer := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	outsider := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	newSigner := mustAddress(t, "t14q6mgxil4ism6a6vp2ee375wfjyionl46wtle5q")
+	msig := mustAddress(t, "t01002")
+	signerID := mustAddress(t, "t0100")
+
+	node.SetID(signer, signerID)
+	node.AddMultisig(msig, []address.Address{signer}, 1, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+
+	lookup := pushAndWait(t, client, proposal(t, outsider, msig, 0, &multisig.ProposeParams{
+		To:     outsider,
+		Value:  abi.NewTokenAmount(1),
+		Method: builtin.MethodSend,
+	}))
+	if lookup.Receipt.ExitCode != exitcode.ErrForbidden {
+		t.Errorf("a proposal from a non signer should be forbidden, got %d", lookup.Receipt.ExitCode)
+	}
+
+	lookup = pushAndWait(t, client, proposal(t, signer, msig, 0, &multisig.ProposeParams{
+		To:     outsider,
+		Value:  abi.NewTokenAmount(100),
+		Method: builtin.MethodSend,
+	}))
+
+	var ret multisig.ProposeReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(lookup.Receipt.Return)); err != nil {
+		t.Fatal(err)
+	}
+	if lookup.Receipt.ExitCode != exitcode.Ok || !ret.Applied || ret.Code != exitcode.Ok || ret.TxnID != 0 {
+		t.Errorf("unexpected result %d %+v", lookup.Receipt.ExitCode, ret)
+	}
+	if !node.Balance(msig).Equals(abi.NewTokenAmount(900)) {
+		t.Errorf("unexpected multisig balance %s", node.Balance(msig))
+	}
+
+	swap := new(bytes.Buffer)
+	if err := (&multisig.SwapSignerParams{From: signer, To: newSigner}).MarshalCBOR(swap); err != nil {
+		t.Fatal(err)
+	}
+	pushAndWait(t, client, proposal(t, signer, msig, 1, &multisig.ProposeParams{
+		To:     msig,
+		Value:  abi.NewTokenAmount(0),
+		Method: builtin.MethodsMultisig.SwapSigner,
+		Params: swap.Bytes(),
+	}))
+
+	state, err := client.StateReadState(context.Background(), msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(state.State, []byte(newSigner.String())) || bytes.Contains(state.State, []byte(signerID.String()+`"`)) {
+		t.Errorf("signer not swapped: %s", state.State)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	signer := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	other := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	msig := mustAddress(t, "t01002")
+	node.AddMultisig(msig, []address.Address{signer, other}, 2, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+	lookup := pushAndWait(t, client, proposal(t, signer, msig, 0, &multisig.ProposeParams{
+		To:     other,
+		Value:  abi.NewTokenAmount(100),
+		Method: builtin.MethodSend,
+	}))
+
+	var ret multisig.ProposeReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(lookup.Receipt.Return)); err != nil {
+		t.Fatal(err)
+	}
+	if ret.Applied {
+		t.Error("the proposal should wait for a second approval")
+	}
+	if len(node.Multisig(msig).Pending) != 1 || !node.Balance(msig).Equals(abi.NewTokenAmount(1000)) {
+		t.Error("the proposal should be pending")
+	}
+
+	hash, err := multisig.ComputeProposalHash(node.Multisig(msig).Pending[ret.TxnID], blake2b.Sum256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup = pushAndWait(t, client, txnIDCall(t, other, msig, 0, builtin.MethodsMultisig.Approve, &multisig.TxnIDParams{
+		ID:           ret.TxnID,
+		ProposalHash: bytes.Repeat([]byte{1}, 32),
+	}))
+	if lookup.Receipt.ExitCode != exitcode.ErrIllegalArgument {
+		t.Errorf("an approval with a wrong proposal hash should fail, got %d", lookup.Receipt.ExitCode)
+	}
+
+	lookup = pushAndWait(t, client, txnIDCall(t, other, msig, 1, builtin.MethodsMultisig.Approve, &multisig.TxnIDParams{
+		ID:           ret.TxnID,
+		ProposalHash: hash,
+	}))
+
+	var approveRet multisig.ApproveReturn
+	if err := approveRet.UnmarshalCBOR(bytes.NewReader(lookup.Receipt.Return)); err != nil {
+		t.Fatal(err)
+	}
+	if !approveRet.Applied || approveRet.Code != exitcode.Ok || !node.Balance(msig).Equals(abi.NewTokenAmount(900)) {
+		t.Errorf("the approved proposal should be applied: %+v", approveRet)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	signer := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	other := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	msig := mustAddress(t, "t01002")
+	node.AddMultisig(msig, []address.Address{signer, other}, 2, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+	pushAndWait(t, client, proposal(t, signer, msig, 0, &multisig.ProposeParams{
+		To:     other,
+		Value:  abi.NewTokenAmount(100),
+		Method: builtin.MethodSend,
+	}))
+
+	lookup := pushAndWait(t, client, txnIDCall(t, other, msig, 0, builtin.MethodsMultisig.Cancel, &multisig.TxnIDParams{ID: 0}))
+	if lookup.Receipt.ExitCode != exitcode.ErrForbidden {
+		t.Errorf("only the proposer can cancel, got %d", lookup.Receipt.ExitCode)
+	}
+
+	lookup = pushAndWait(t, client, txnIDCall(t, signer, msig, 1, builtin.MethodsMultisig.Cancel, &multisig.TxnIDParams{ID: 0}))
+	if lookup.Receipt.ExitCode != exitcode.Ok || len(node.Multisig(msig).Pending) != 0 {
+		t.Errorf("the proposal should be cancelled, got %d", lookup.Receipt.ExitCode)
+	}
+}
+
+func TestSignerManagement(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	signer := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	other := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	msig := mustAddress(t, "t01002")
+	node.AddMultisig(msig, []address.Address{signer}, 1, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+	if code := selfProposal(t, client, signer, msig, 0, builtin.MethodsMultisig.AddSigner, &multisig.AddSignerParams{Signer: other, Increase: true}); code != exitcode.Ok {
+		t.Fatalf("unexpected AddSigner exit code %d", code)
+	}
+	if m := node.Multisig(msig); len(m.Signers) != 2 || m.NumApprovalsThreshold != 2 {
+		t.Errorf("unexpected multisig after AddSigner %+v", m)
+	}
+
+	// With a threshold of 2 the next proposals need the approval of other
+	if code := selfProposal(t, client, signer, msig, 1, builtin.MethodsMultisig.ChangeNumApprovalsThreshold, &multisig.ChangeNumApprovalsThresholdParams{NewThreshold: 1}); code != exitcode.Ok {
+		t.Fatalf("unexpected ChangeNumApprovalsThreshold exit code %d", code)
+	}
+	if m := node.Multisig(msig); len(m.Pending) != 1 || m.NumApprovalsThreshold != 2 {
+		t.Errorf("the threshold change should be pending %+v", m)
+	}
+
+	pushAndWait(t, client, txnIDCall(t, other, msig, 0, builtin.MethodsMultisig.Approve, &multisig.TxnIDParams{ID: 1}))
+	if m := node.Multisig(msig); m.NumApprovalsThreshold != 1 {
+		t.Errorf("unexpected threshold %d", m.NumApprovalsThreshold)
+	}
+
+	if code := selfProposal(t, client, signer, msig, 2, builtin.MethodsMultisig.RemoveSigner, &multisig.RemoveSignerParams{Signer: other}); code != exitcode.Ok {
+		t.Fatalf("unexpected RemoveSigner exit code %d", code)
+	}
+	if m := node.Multisig(msig); len(m.Signers) != 1 || m.Signers[0] != signer {
+		t.Errorf("unexpected signers after RemoveSigner %v", m.Signers)
+	}
+
+	if code := selfProposal(t, client, signer, msig, 3, builtin.MethodsMultisig.RemoveSigner, &multisig.RemoveSignerParams{Signer: signer}); code != exitcode.ErrForbidden {
+		t.Errorf("the last signer should not be removable, got %d", code)
+	}
+}
+
+func TestLockBalance(t *testing.T) {
+	node := NewNode(verifyNonEmpty)
+	defer node.Close()
+
+	signer := mustAddress(t, "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	other := mustAddress(t, "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	msig := mustAddress(t, "t01002")
+	node.AddMultisig(msig, []address.Address{signer}, 1, abi.NewTokenAmount(1000))
+
+	client := lotusclient.NewClient(node.URL, "")
+
+	// Each message is included one epoch after the previous one, starting at 1
+	lock := &multisig.LockBalanceParams{StartEpoch: 0, UnlockDuration: 10, Amount: abi.NewTokenAmount(1000)}
+	if code := selfProposal(t, client, signer, msig, 0, builtin.MethodsMultisig.LockBalance, lock); code != exitcode.Ok {
+		t.Fatalf("unexpected LockBalance exit code %d", code)
+	}
+
+	// At epoch 2, 800 are still locked
+	lookup := pushAndWait(t, client, proposal(t, signer, msig, 1, &multisig.ProposeParams{
+		To:     other,
+		Value:  abi.NewTokenAmount(500),
+		Method: builtin.MethodSend,
+	}))
+	if lookup.Receipt.ExitCode != exitcode.ErrInsufficientFunds || !node.Balance(msig).Equals(abi.NewTokenAmount(1000)) {
+		t.Errorf("spending locked funds should fail, got %d", lookup.Receipt.ExitCode)
+	}
+
+	// At epoch 3, 700 are still locked
+	lookup = pushAndWait(t, client, proposal(t, signer, msig, 2, &multisig.ProposeParams{
+		To:     other,
+		Value:  abi.NewTokenAmount(300),
+		Method: builtin.MethodSend,
+	}))
+	if lookup.Receipt.ExitCode != exitcode.Ok || !node.Balance(msig).Equals(abi.NewTokenAmount(700)) {
+		t.Errorf("spending unlocked funds should succeed, got %d", lookup.Receipt.ExitCode)
+	}
+
+	if code := selfProposal(t, client, signer, msig, 3, builtin.MethodsMultisig.LockBalance, lock); code != exitcode.ErrForbidden {
+		t.Errorf("the vesting schedule should not be modifiable, got %d", code)
+	}
+}
+
+func TestActorAddress(t *testing.T) {
+	creator, err := address.NewIDAddress(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := actorAddress(creator, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The nonce is not truncated to a byte
+	other, err := actorAddress(creator, 257)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == other {
+		t.Errorf("nonces 1 and 257 give the same address %s", first)
+	}
+}
@@ -0,0 +1,77 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+)
+
+func (r RosettaConstructionFilecoin) ConstructMarketAddBalance(request *MarketAddBalanceRequest) (string, error) {
+	value, err := validateAmount("quantity", request.Quantity)
+	if err != nil {
+		return "", err
+	}
+
+	if value.IsZero() {
+		return "", fmt.Errorf("quantity must be positive")
+	}
+
+	account := request.Params.ProviderOrClient
+	if account == "" {
+		account = request.From
+	}
+
+	providerOrClient, err := r.parseAddress(account)
+	if err != nil {
+		return "", err
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.StorageMarketActorAddr), request.From, "", value, &request.Metadata,
+		builtin.MethodsMarket.AddBalance, &providerOrClient)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMarketWithdrawBalance(request *MarketWithdrawBalanceRequest) (string, error) {
+	amount, err := validateAmount("amount", request.Params.Amount)
+	if err != nil {
+		return "", err
+	}
+
+	if amount.IsZero() {
+		return "", fmt.Errorf("amount must be positive")
+	}
+
+	account := request.Params.ProviderOrClient
+	if account == "" {
+		account = request.From
+	}
+
+	providerOrClient, err := r.parseAddress(account)
+	if err != nil {
+		return "", err
+	}
+
+	withdrawParams := &market.WithdrawBalanceParams{
+		ProviderOrClientAddress: providerOrClient,
+		Amount:                  amount,
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.StorageMarketActorAddr), request.From, "", big.Zero(), &request.Metadata,
+		builtin.MethodsMarket.WithdrawBalance, withdrawParams)
+}
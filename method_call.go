@@ -0,0 +1,182 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// methodParams returns a new value of the specs-actors params type of a method, nil for a method without params
+type methodParams func() cbg.CBORMarshaler
+
+// actorMethodParams are the params types of the methods that ConstructMethodCall can encode from JSON
+var actorMethodParams = map[string]map[abi.MethodNum]methodParams{
+	ActorInit: {
+		builtin.MethodsInit.Exec: func() cbg.CBORMarshaler { return new(init_.ExecParams) },
+	},
+	ActorMultisig: {
+		builtin.MethodsMultisig.Propose:                     func() cbg.CBORMarshaler { return new(multisig.ProposeParams) },
+		builtin.MethodsMultisig.Approve:                     func() cbg.CBORMarshaler { return new(multisig.TxnIDParams) },
+		builtin.MethodsMultisig.Cancel:                      func() cbg.CBORMarshaler { return new(multisig.TxnIDParams) },
+		builtin.MethodsMultisig.AddSigner:                   func() cbg.CBORMarshaler { return new(multisig.AddSignerParams) },
+		builtin.MethodsMultisig.RemoveSigner:                func() cbg.CBORMarshaler { return new(multisig.RemoveSignerParams) },
+		builtin.MethodsMultisig.SwapSigner:                  func() cbg.CBORMarshaler { return new(multisig.SwapSignerParams) },
+		builtin.MethodsMultisig.ChangeNumApprovalsThreshold: func() cbg.CBORMarshaler { return new(multisig.ChangeNumApprovalsThresholdParams) },
+		builtin.MethodsMultisig.LockBalance:                 func() cbg.CBORMarshaler { return new(multisig.LockBalanceParams) },
+	},
+	ActorMiner: {
+		builtin.MethodsMiner.ControlAddresses:    nil,
+		builtin.MethodsMiner.ChangeWorkerAddress: func() cbg.CBORMarshaler { return new(miner.ChangeWorkerAddressParams) },
+		builtin.MethodsMiner.ChangePeerID:        func() cbg.CBORMarshaler { return new(miner.ChangePeerIDParams) },
+		builtin.MethodsMiner.ChangeMultiaddrs:    func() cbg.CBORMarshaler { return new(miner.ChangeMultiaddrsParams) },
+		builtin.MethodsMiner.WithdrawBalance:     func() cbg.CBORMarshaler { return new(miner.WithdrawBalanceParams) },
+	},
+	ActorMarket: {
+		builtin.MethodsMarket.AddBalance:      func() cbg.CBORMarshaler { return new(address.Address) },
+		builtin.MethodsMarket.WithdrawBalance: func() cbg.CBORMarshaler { return new(market.WithdrawBalanceParams) },
+	},
+	ActorPaych: {
+		builtin.MethodsPaych.UpdateChannelState: func() cbg.CBORMarshaler { return new(paych.UpdateChannelStateParams) },
+		builtin.MethodsPaych.Settle:             nil,
+		builtin.MethodsPaych.Collect:            nil,
+	},
+	ActorVerifreg: {
+		builtin.MethodsVerifiedRegistry.AddVerifier:       func() cbg.CBORMarshaler { return new(verifreg.AddVerifierParams) },
+		builtin.MethodsVerifiedRegistry.RemoveVerifier:    func() cbg.CBORMarshaler { return new(address.Address) },
+		builtin.MethodsVerifiedRegistry.AddVerifiedClient: func() cbg.CBORMarshaler { return new(verifreg.AddVerifiedClientParams) },
+	},
+}
+
+// encodeJSONParams encodes the JSON description of the params of a method of actor in CBOR
+func encodeJSONParams(actor string, method abi.MethodNum, jsonParams json.RawMessage) ([]byte, error) {
+	methods, ok := actorMethodParams[actor]
+	if !ok {
+		return nil, fmt.Errorf("unknown actor '%s'", actor)
+	}
+
+	newParams, ok := methods[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %d of the %s actor", method, actor)
+	}
+
+	empty := len(bytes.TrimSpace(jsonParams)) == 0 || bytes.Equal(bytes.TrimSpace(jsonParams), []byte("null"))
+	if newParams == nil {
+		if !empty {
+			return nil, fmt.Errorf("method %d of the %s actor takes no params", method, actor)
+		}
+		return []byte{}, nil
+	}
+
+	if empty {
+		return nil, fmt.Errorf("missing params of method %d of the %s actor", method, actor)
+	}
+
+	params := newParams()
+	decoder := json.NewDecoder(bytes.NewReader(jsonParams))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params of method %d of the %s actor: %v", method, actor, err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = params.MarshalCBOR(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r RosettaConstructionFilecoin) ConstructMethodCall(request *MethodCallRequest) (string, error) {
+	to, err := r.parseAddress(request.To)
+	if err != nil {
+		return "", err
+	}
+
+	from, err := r.parseAddress(request.From)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := validateAmount("quantity", request.Quantity)
+	if err != nil {
+		return "", err
+	}
+
+	gasfeecap, gaspremium, err := validateGas(&request.Metadata)
+	if err != nil {
+		return "", err
+	}
+	gaslimit := request.Metadata.GasLimit
+
+	method := abi.MethodNum(request.Method)
+	params := request.Params
+	if len(request.JSONParams) != 0 {
+		if len(request.Params) != 0 {
+			return "", fmt.Errorf("params and JSON params are exclusive")
+		}
+
+		// Singleton actors are recognized by their address, the others must be named
+		actor := request.Actor
+		if actor == "" {
+			if actors := actorsOf(to); len(actors) == 1 {
+				actor = actors[0]
+			}
+		}
+
+		if actor == "" {
+			return "", fmt.Errorf("the actor is required to encode JSON params for %s", request.To)
+		}
+
+		params, err = encodeJSONParams(actor, method, request.JSONParams)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if params == nil {
+		params = make([]byte, 0)
+	}
+
+	msg := &types.Message{Version: types.MessageVersion,
+		To:         to,
+		From:       from,
+		Nonce:      request.Metadata.Nonce,
+		Value:      value,
+		GasFeeCap:  gasfeecap,
+		GasPremium: gaspremium,
+		GasLimit:   gaslimit,
+		Method:     method,
+		Params:     params,
+	}
+
+	return r.encodeMessage(msg)
+}
@@ -0,0 +1,83 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/multiformats/go-multihash"
+)
+
+func (r RosettaConstructionFilecoin) ConstructMinerWithdrawBalance(request *MinerWithdrawBalanceRequest) (string, error) {
+	amount, err := validateAmount("amount requested", request.Params.AmountRequested)
+	if err != nil {
+		return "", err
+	}
+
+	if amount.IsZero() {
+		return "", fmt.Errorf("amount requested must be positive")
+	}
+
+	withdrawParams := &miner.WithdrawBalanceParams{
+		AmountRequested: amount,
+	}
+
+	return r.constructActorCall(request.Miner, request.From, request.Multisig, big.Zero(), &request.Metadata,
+		builtin.MethodsMiner.WithdrawBalance, withdrawParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMinerChangeWorkerAddress(request *MinerChangeWorkerAddressRequest) (string, error) {
+	worker, err := r.parseAddress(request.Params.NewWorker)
+	if err != nil {
+		return "", err
+	}
+
+	controlAddrs := make([]address.Address, 0, len(request.Params.NewControlAddrs))
+	for _, controlAddr := range request.Params.NewControlAddrs {
+		addr, err := r.parseAddress(controlAddr)
+		if err != nil {
+			return "", err
+		}
+		controlAddrs = append(controlAddrs, addr)
+	}
+
+	changeParams := &miner.ChangeWorkerAddressParams{
+		NewWorker:       worker,
+		NewControlAddrs: controlAddrs,
+	}
+
+	return r.constructActorCall(request.Miner, request.From, request.Multisig, big.Zero(), &request.Metadata,
+		builtin.MethodsMiner.ChangeWorkerAddress, changeParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructMinerChangePeerID(request *MinerChangePeerIDRequest) (string, error) {
+	peerID, err := multihash.FromB58String(request.Params.NewID)
+	if err != nil {
+		return "", fmt.Errorf("invalid peer ID '%s': %v", request.Params.NewID, err)
+	}
+
+	changeParams := &miner.ChangePeerIDParams{
+		NewID: abi.PeerID(peerID),
+	}
+
+	return r.constructActorCall(request.Miner, request.From, request.Multisig, big.Zero(), &request.Metadata,
+		builtin.MethodsMiner.ChangePeerID, changeParams)
+}
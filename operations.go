@@ -0,0 +1,674 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+)
+
+// Operation types
+const (
+	// OperationSend is a FIL transfer, described by two operations: the sender with a negative
+	// amount followed by the receiver with the opposite amount
+	OperationSend = "Send"
+	// OperationPropose is a multisig send proposal: the account is the proposer, the amount is the value
+	// to send, the "multisig" and "to" metadata are the multisig and the destination
+	OperationPropose = "Propose"
+	// OperationSwapSigner is a multisig signer swap proposal: the account is the proposer, the "multisig",
+	// "old_signer" and "new_signer" metadata are the multisig and the swapped signers
+	OperationSwapSigner = "SwapSigner"
+	// OperationAddSigner is a multisig proposal to add a signer: the account is the proposer, the "multisig",
+	// "signer" and optional "increase" (raise the threshold) metadata are the multisig and the new signer
+	OperationAddSigner = "AddSigner"
+	// OperationRemoveSigner is a multisig proposal to remove a signer: the account is the proposer, the "multisig",
+	// "signer" and optional "decrease" (lower the threshold) metadata are the multisig and the removed signer
+	OperationRemoveSigner = "RemoveSigner"
+	// OperationChangeNumApprovalsThreshold is a multisig proposal to change the threshold: the account is
+	// the proposer, the "multisig" and "new_threshold" metadata are the multisig and its new threshold
+	OperationChangeNumApprovalsThreshold = "ChangeNumApprovalsThreshold"
+	// OperationLockBalance is a multisig proposal to lock part of its balance: the account is the proposer,
+	// the "multisig", "start_epoch", "unlock_duration" and "lock_amount" metadata are the multisig and its vesting
+	OperationLockBalance = "LockBalance"
+	// OperationCreateMultisig creates a multisig: the account is the creator, the amount is the initial balance,
+	// the "signers" (comma separated), "threshold" and optional "unlock_duration" metadata are the multisig parameters
+	OperationCreateMultisig = "CreateMultisig"
+	// OperationApprove approves a pending multisig transaction: the account is the approver, the "multisig",
+	// "txn_id" and optional "proposal_hash" (hex) metadata identify the transaction
+	OperationApprove = "Approve"
+	// OperationCancel cancels a pending multisig transaction: the account is the proposer, the metadata
+	// are the same as OperationApprove
+	OperationCancel = "Cancel"
+	// OperationMarketAddBalance deposits into the storage market escrow: the account is the sender, the amount is
+	// the deposit, the "provider_or_client" metadata is the escrow account credited
+	OperationMarketAddBalance = "MarketAddBalance"
+	// OperationMarketWithdrawBalance withdraws from the storage market escrow: the account is the sender, the amount
+	// is the requested amount, the "provider_or_client" metadata is the escrow account debited
+	OperationMarketWithdrawBalance = "MarketWithdrawBalance"
+)
+
+// Operation metadata keys
+const (
+	MetadataMultisig         = "multisig"
+	MetadataTo               = "to"
+	MetadataOldSigner        = "old_signer"
+	MetadataNewSigner        = "new_signer"
+	MetadataSigner           = "signer"
+	MetadataIncrease         = "increase"
+	MetadataDecrease         = "decrease"
+	MetadataNewThreshold     = "new_threshold"
+	MetadataSigners          = "signers"
+	MetadataThreshold        = "threshold"
+	MetadataUnlockDuration   = "unlock_duration"
+	MetadataStartEpoch       = "start_epoch"
+	MetadataLockAmount       = "lock_amount"
+	MetadataTxnID            = "txn_id"
+	MetadataProposalHash     = "proposal_hash"
+	MetadataProviderOrClient = "provider_or_client"
+)
+
+// Operation is a Rosetta style description of what a transaction does
+type Operation struct {
+	Type     string            `json:"type"`
+	Account  string            `json:"account"`
+	Amount   abi.TokenAmount   `json:"amount"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (r RosettaConstructionFilecoin) ConstructFromOperations(operations []Operation, metadata TxMetadata) (string, error) {
+	if len(operations) == 0 {
+		return "", fmt.Errorf("no operations")
+	}
+
+	switch operations[0].Type {
+	case OperationSend:
+		if len(operations) != 2 || operations[1].Type != OperationSend {
+			return "", fmt.Errorf("a transfer is described by two Send operations")
+		}
+
+		sender, receiver := operations[0], operations[1]
+		if sender.Amount.Nil() || receiver.Amount.Nil() || !sender.Amount.Neg().Equals(receiver.Amount) {
+			return "", fmt.Errorf("Send operations must move the same amount from the sender to the receiver")
+		}
+
+		return r.ConstructPayment(&PaymentRequest{
+			From:     sender.Account,
+			To:       receiver.Account,
+			Quantity: receiver.Amount,
+			Metadata: metadata,
+		})
+
+	case OperationPropose:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a proposal is described by a single Propose operation")
+		}
+
+		op := operations[0]
+		return r.ConstructMultisigPayment(&MultisigPaymentRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params: MultisigPaymentParams{
+				To:       op.Metadata[MetadataTo],
+				Quantity: op.Amount,
+			},
+		})
+
+	case OperationSwapSigner:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a signer swap is described by a single SwapSigner operation")
+		}
+
+		op := operations[0]
+		return r.ConstructSwapAuthorizedParty(&SwapAuthorizedPartyRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params: SwapAuthorizedPartyParams{
+				From: op.Metadata[MetadataOldSigner],
+				To:   op.Metadata[MetadataNewSigner],
+			},
+		})
+
+	case OperationAddSigner, OperationRemoveSigner, OperationChangeNumApprovalsThreshold:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a %s is described by a single operation", operations[0].Type)
+		}
+
+		return r.constructSignerManagement(&operations[0], metadata)
+
+	case OperationLockBalance:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a balance lock is described by a single LockBalance operation")
+		}
+
+		op := operations[0]
+		params, err := lockBalanceParamsFromMetadata(op.Metadata)
+		if err != nil {
+			return "", err
+		}
+
+		return r.ConstructLockBalance(&LockBalanceRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params:   *params,
+		})
+
+	case OperationCreateMultisig:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a multisig creation is described by a single CreateMultisig operation")
+		}
+
+		op := operations[0]
+		params, err := multisigCreateParamsFromMetadata(op.Metadata)
+		if err != nil {
+			return "", err
+		}
+
+		return r.ConstructMultisigCreate(&MultisigCreateRequest{
+			From:     op.Account,
+			Quantity: op.Amount,
+			Metadata: metadata,
+			Params:   *params,
+		})
+
+	case OperationApprove, OperationCancel:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a %s is described by a single operation", operations[0].Type)
+		}
+
+		op := operations[0]
+		params, err := txnIDParamsFromMetadata(op.Metadata)
+		if err != nil {
+			return "", err
+		}
+
+		if op.Type == OperationApprove {
+			return r.ConstructMultisigApprove(&MultisigApproveRequest{
+				Multisig: op.Metadata[MetadataMultisig],
+				From:     op.Account,
+				Metadata: metadata,
+				Params:   *params,
+			})
+		}
+
+		return r.ConstructMultisigCancel(&MultisigCancelRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params:   *params,
+		})
+
+	case OperationMarketAddBalance, OperationMarketWithdrawBalance:
+		if len(operations) != 1 {
+			return "", fmt.Errorf("a %s is described by a single operation", operations[0].Type)
+		}
+
+		op := operations[0]
+		if op.Type == OperationMarketAddBalance {
+			return r.ConstructMarketAddBalance(&MarketAddBalanceRequest{
+				From:     op.Account,
+				Quantity: op.Amount,
+				Metadata: metadata,
+				Params:   MarketAddBalanceParams{ProviderOrClient: op.Metadata[MetadataProviderOrClient]},
+			})
+		}
+
+		return r.ConstructMarketWithdrawBalance(&MarketWithdrawBalanceRequest{
+			From:     op.Account,
+			Metadata: metadata,
+			Params: MarketWithdrawBalanceParams{
+				ProviderOrClient: op.Metadata[MetadataProviderOrClient],
+				Amount:           op.Amount,
+			},
+		})
+
+	default:
+		return "", fmt.Errorf("unsupported operation type %s", operations[0].Type)
+	}
+}
+
+// constructSignerManagement creates the AddSigner, RemoveSigner and ChangeNumApprovalsThreshold proposals
+func (r RosettaConstructionFilecoin) constructSignerManagement(op *Operation, metadata TxMetadata) (string, error) {
+	switch op.Type {
+	case OperationAddSigner:
+		increase, err := optionalBool(op.Metadata, MetadataIncrease)
+		if err != nil {
+			return "", err
+		}
+
+		return r.ConstructAddSigner(&AddSignerRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params:   AddSignerParams{Signer: op.Metadata[MetadataSigner], Increase: increase},
+		})
+
+	case OperationRemoveSigner:
+		decrease, err := optionalBool(op.Metadata, MetadataDecrease)
+		if err != nil {
+			return "", err
+		}
+
+		return r.ConstructRemoveSigner(&RemoveSignerRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params:   RemoveSignerParams{Signer: op.Metadata[MetadataSigner], Decrease: decrease},
+		})
+
+	default:
+		threshold, err := strconv.ParseUint(op.Metadata[MetadataNewThreshold], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s metadata: %v", MetadataNewThreshold, err)
+		}
+
+		return r.ConstructChangeNumApprovalsThreshold(&ChangeNumApprovalsThresholdRequest{
+			Multisig: op.Metadata[MetadataMultisig],
+			From:     op.Account,
+			Metadata: metadata,
+			Params:   ChangeNumApprovalsThresholdParams{NewThreshold: threshold},
+		})
+	}
+}
+
+// optionalBool reads a boolean metadata, false when absent
+func optionalBool(metadata map[string]string, key string) (bool, error) {
+	encoded, ok := metadata[key]
+	if !ok {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(encoded)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s metadata: %v", key, err)
+	}
+	return value, nil
+}
+
+// lockBalanceParamsFromMetadata reads the parameters of LockBalance operations
+func lockBalanceParamsFromMetadata(metadata map[string]string) (*LockBalanceParams, error) {
+	startEpoch, err := strconv.ParseInt(metadata[MetadataStartEpoch], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %v", MetadataStartEpoch, err)
+	}
+
+	unlockDuration, err := strconv.ParseInt(metadata[MetadataUnlockDuration], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %v", MetadataUnlockDuration, err)
+	}
+
+	amount, err := big.FromString(metadata[MetadataLockAmount])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %v", MetadataLockAmount, err)
+	}
+
+	return &LockBalanceParams{
+		StartEpoch:     startEpoch,
+		UnlockDuration: unlockDuration,
+		Amount:         amount,
+	}, nil
+}
+
+// multisigCreateParamsFromMetadata reads the parameters of CreateMultisig operations
+func multisigCreateParamsFromMetadata(metadata map[string]string) (*MultisigCreateParams, error) {
+	var signers []string
+	if metadata[MetadataSigners] != "" {
+		signers = strings.Split(metadata[MetadataSigners], ",")
+	}
+
+	threshold, err := strconv.ParseUint(metadata[MetadataThreshold], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %v", MetadataThreshold, err)
+	}
+
+	var unlockDuration int64
+	if encoded, ok := metadata[MetadataUnlockDuration]; ok {
+		unlockDuration, err = strconv.ParseInt(encoded, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s metadata: %v", MetadataUnlockDuration, err)
+		}
+	}
+
+	return &MultisigCreateParams{
+		Signers:        signers,
+		Threshold:      threshold,
+		UnlockDuration: unlockDuration,
+	}, nil
+}
+
+// txnIDParamsFromMetadata reads the transaction ID and proposal hash of Approve and Cancel operations
+func txnIDParamsFromMetadata(metadata map[string]string) (*TxnIDParams, error) {
+	txnID, err := strconv.ParseInt(metadata[MetadataTxnID], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %v", MetadataTxnID, err)
+	}
+
+	var proposalHash []byte
+	if encoded, ok := metadata[MetadataProposalHash]; ok {
+		proposalHash, err = hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s metadata: %v", MetadataProposalHash, err)
+		}
+	}
+
+	return &TxnIDParams{TxnID: txnID, ProposalHash: proposalHash}, nil
+}
+
+func (r RosettaConstructionFilecoin) ParseToOperations(tx string) ([]Operation, error) {
+	msg, sm, err := DecodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm != nil {
+		msg = &sm.Message
+	}
+
+	return r.messageToOperations(msg)
+}
+
+// messageToOperations describes msg as Rosetta style operations
+func (r RosettaConstructionFilecoin) messageToOperations(msg *types.Message) ([]Operation, error) {
+	if msg.Method == builtin.MethodSend {
+		return []Operation{
+			{
+				Type:    OperationSend,
+				Account: r.formatAddress(msg.From),
+				Amount:  msg.Value.Neg(),
+			},
+			{
+				Type:    OperationSend,
+				Account: r.formatAddress(msg.To),
+				Amount:  msg.Value,
+			},
+		}, nil
+	}
+
+	// Method numbers are specific to each actor, singleton actors are recognized by their address
+	switch {
+	case msg.To == builtin.InitActorAddr:
+		if msg.Method != builtin.MethodsInit.Exec {
+			return nil, fmt.Errorf("unsupported method %d of the init actor", msg.Method)
+		}
+		return r.execToOperations(msg)
+
+	case msg.To == builtin.StorageMarketActorAddr:
+		return r.marketToOperations(msg)
+
+	case msg.To == builtin.VerifiedRegistryActorAddr:
+		// Datacap is not a balance, verified registry calls are not described by operations
+		return nil, unsupportedCallError(r.parseCall(msg.To, msg.Value, msg.Method, msg.Params, []string{ActorVerifreg}))
+
+	case isSingleton(msg.To):
+		return nil, fmt.Errorf("unsupported method %d of the singleton actor %s", msg.Method, r.formatAddress(msg.To))
+	}
+
+	// The other actors are told apart by the params of their methods, only multisig calls are operations
+	call := r.parseCall(msg.To, msg.Value, msg.Method, msg.Params, actorsOf(msg.To))
+	if call.Actor != ActorMultisig {
+		return nil, unsupportedCallError(call)
+	}
+
+	switch msg.Method {
+	case builtin.MethodsMultisig.Propose:
+		return r.proposeToOperations(msg)
+
+	case builtin.MethodsMultisig.Approve:
+		return r.txnIDToOperations(OperationApprove, msg)
+
+	case builtin.MethodsMultisig.Cancel:
+		return r.txnIDToOperations(OperationCancel, msg)
+
+	default:
+		return nil, unsupportedCallError(call)
+	}
+}
+
+// unsupportedCallError describes a call that has no operations
+func unsupportedCallError(call *ParsedCall) error {
+	if call.Actor == "" {
+		return fmt.Errorf("unsupported method %d of %s, unknown actor or params", call.Method, call.To)
+	}
+	return fmt.Errorf("unsupported method %s (%d) of the %s actor %s", call.MethodName, call.Method, call.Actor, call.To)
+}
+
+func (r RosettaConstructionFilecoin) proposeToOperations(msg *types.Message) ([]Operation, error) {
+	if !msg.Value.NilOrZero() {
+		return nil, fmt.Errorf("proposals cannot carry value")
+	}
+
+	var params multisig.ProposeParams
+	err := params.UnmarshalCBOR(bytes.NewReader(msg.Params))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case params.Method == builtin.MethodSend && len(params.Params) == 0:
+		return []Operation{{
+			Type:    OperationPropose,
+			Account: r.formatAddress(msg.From),
+			Amount:  params.Value,
+			Metadata: map[string]string{
+				MetadataMultisig: r.formatAddress(msg.To),
+				MetadataTo:       r.formatAddress(params.To),
+			},
+		}}, nil
+
+	case params.To == msg.To && params.Value.NilOrZero():
+		return r.selfProposalToOperations(msg, &params)
+
+	default:
+		return nil, fmt.Errorf("unsupported proposal of method %d to %s", params.Method, params.To)
+	}
+}
+
+// selfProposalToOperations describes the signer management and balance lock proposals of a multisig
+func (r RosettaConstructionFilecoin) selfProposalToOperations(msg *types.Message, params *multisig.ProposeParams) ([]Operation, error) {
+	metadata := map[string]string{
+		MetadataMultisig: r.formatAddress(msg.To),
+	}
+
+	var operationType string
+	switch params.Method {
+	case builtin.MethodsMultisig.SwapSigner:
+		var swap multisig.SwapSignerParams
+		err := swap.UnmarshalCBOR(bytes.NewReader(params.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		operationType = OperationSwapSigner
+		metadata[MetadataOldSigner] = r.formatAddress(swap.From)
+		metadata[MetadataNewSigner] = r.formatAddress(swap.To)
+
+	case builtin.MethodsMultisig.AddSigner:
+		var add multisig.AddSignerParams
+		err := add.UnmarshalCBOR(bytes.NewReader(params.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		operationType = OperationAddSigner
+		metadata[MetadataSigner] = r.formatAddress(add.Signer)
+		if add.Increase {
+			metadata[MetadataIncrease] = strconv.FormatBool(add.Increase)
+		}
+
+	case builtin.MethodsMultisig.RemoveSigner:
+		var remove multisig.RemoveSignerParams
+		err := remove.UnmarshalCBOR(bytes.NewReader(params.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		operationType = OperationRemoveSigner
+		metadata[MetadataSigner] = r.formatAddress(remove.Signer)
+		if remove.Decrease {
+			metadata[MetadataDecrease] = strconv.FormatBool(remove.Decrease)
+		}
+
+	case builtin.MethodsMultisig.ChangeNumApprovalsThreshold:
+		var threshold multisig.ChangeNumApprovalsThresholdParams
+		err := threshold.UnmarshalCBOR(bytes.NewReader(params.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		operationType = OperationChangeNumApprovalsThreshold
+		metadata[MetadataNewThreshold] = strconv.FormatUint(threshold.NewThreshold, 10)
+
+	case builtin.MethodsMultisig.LockBalance:
+		var lock multisig.LockBalanceParams
+		err := lock.UnmarshalCBOR(bytes.NewReader(params.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		operationType = OperationLockBalance
+		metadata[MetadataStartEpoch] = strconv.FormatInt(int64(lock.StartEpoch), 10)
+		metadata[MetadataUnlockDuration] = strconv.FormatInt(int64(lock.UnlockDuration), 10)
+		metadata[MetadataLockAmount] = lock.Amount.String()
+
+	default:
+		return nil, fmt.Errorf("unsupported proposal of method %d to the multisig itself", params.Method)
+	}
+
+	return []Operation{{
+		Type:     operationType,
+		Account:  r.formatAddress(msg.From),
+		Amount:   big.Zero(),
+		Metadata: metadata,
+	}}, nil
+}
+
+func (r RosettaConstructionFilecoin) txnIDToOperations(operationType string, msg *types.Message) ([]Operation, error) {
+	if !msg.Value.NilOrZero() {
+		return nil, fmt.Errorf("%s calls cannot carry value", operationType)
+	}
+
+	var params multisig.TxnIDParams
+	err := params.UnmarshalCBOR(bytes.NewReader(msg.Params))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		MetadataMultisig: r.formatAddress(msg.To),
+		MetadataTxnID:    strconv.FormatInt(int64(params.ID), 10),
+	}
+	if len(params.ProposalHash) != 0 {
+		metadata[MetadataProposalHash] = hex.EncodeToString(params.ProposalHash)
+	}
+
+	return []Operation{{
+		Type:     operationType,
+		Account:  r.formatAddress(msg.From),
+		Amount:   big.Zero(),
+		Metadata: metadata,
+	}}, nil
+}
+
+func (r RosettaConstructionFilecoin) marketToOperations(msg *types.Message) ([]Operation, error) {
+	switch msg.Method {
+	case builtin.MethodsMarket.AddBalance:
+		var providerOrClient address.Address
+		err := providerOrClient.UnmarshalCBOR(bytes.NewReader(msg.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		return []Operation{{
+			Type:     OperationMarketAddBalance,
+			Account:  r.formatAddress(msg.From),
+			Amount:   msg.Value,
+			Metadata: map[string]string{MetadataProviderOrClient: r.formatAddress(providerOrClient)},
+		}}, nil
+
+	case builtin.MethodsMarket.WithdrawBalance:
+		if !msg.Value.NilOrZero() {
+			return nil, fmt.Errorf("%s calls cannot carry value", OperationMarketWithdrawBalance)
+		}
+
+		var params market.WithdrawBalanceParams
+		err := params.UnmarshalCBOR(bytes.NewReader(msg.Params))
+		if err != nil {
+			return nil, err
+		}
+
+		return []Operation{{
+			Type:     OperationMarketWithdrawBalance,
+			Account:  r.formatAddress(msg.From),
+			Amount:   params.Amount,
+			Metadata: map[string]string{MetadataProviderOrClient: r.formatAddress(params.ProviderOrClientAddress)},
+		}}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported method %d of the storage market actor", msg.Method)
+	}
+}
+
+func (r RosettaConstructionFilecoin) execToOperations(msg *types.Message) ([]Operation, error) {
+	var params init_.ExecParams
+	err := params.UnmarshalCBOR(bytes.NewReader(msg.Params))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.CodeCID != builtin.MultisigActorCodeID {
+		return nil, fmt.Errorf("unsupported creation of actor %s", params.CodeCID)
+	}
+
+	var constructorParams multisig.ConstructorParams
+	err = constructorParams.UnmarshalCBOR(bytes.NewReader(params.ConstructorParams))
+	if err != nil {
+		return nil, err
+	}
+
+	signers := make([]string, len(constructorParams.Signers))
+	for i, signer := range constructorParams.Signers {
+		signers[i] = r.formatAddress(signer)
+	}
+
+	metadata := map[string]string{
+		MetadataSigners:   strings.Join(signers, ","),
+		MetadataThreshold: strconv.FormatUint(constructorParams.NumApprovalsThreshold, 10),
+	}
+	if constructorParams.UnlockDuration != 0 {
+		metadata[MetadataUnlockDuration] = strconv.FormatInt(int64(constructorParams.UnlockDuration), 10)
+	}
+
+	return []Operation{{
+		Type:     OperationCreateMultisig,
+		Account:  r.formatAddress(msg.From),
+		Amount:   msg.Value,
+		Metadata: metadata,
+	}}, nil
+}
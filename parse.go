@@ -0,0 +1,485 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// Actors whose methods are decoded by ParseTxDetailed
+const (
+	ActorInit     = "init"
+	ActorMultisig = "multisig"
+	ActorMiner    = "miner"
+	ActorMarket   = "market"
+	ActorPaych    = "paych"
+	ActorVerifreg = "verifreg"
+)
+
+// ParsedCall is an actor method call, with its params decoded when the actor method is known
+type ParsedCall struct {
+	To     string          `json:"to"`
+	Value  abi.TokenAmount `json:"value"`
+	Method uint64          `json:"method"`
+	// Actor and MethodName identify the method, they are empty when it is not known
+	Actor      string `json:"actor,omitempty"`
+	MethodName string `json:"method_name,omitempty"`
+	// Params are the decoded params, addresses are formatted for the configured network
+	Params map[string]interface{} `json:"params,omitempty"`
+	// RawParams are the params that could not be decoded
+	RawParams []byte `json:"raw_params,omitempty"`
+}
+
+// ParsedTx is a transaction with the params of its method decoded, as returned by ParseTxDetailed
+type ParsedTx struct {
+	ParsedCall
+	From       string          `json:"from"`
+	Nonce      uint64          `json:"nonce"`
+	GasLimit   int64           `json:"gas_limit"`
+	GasFeeCap  abi.TokenAmount `json:"gas_fee_cap"`
+	GasPremium abi.TokenAmount `json:"gas_premium"`
+	// Signature is only set for signed transactions
+	Signature *crypto.Signature `json:"signature,omitempty"`
+}
+
+func (r RosettaConstructionFilecoin) ParseTxDetailed(tx string) (*ParsedTx, error) {
+	msg, sm, err := DecodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature *crypto.Signature
+	if sm != nil {
+		msg = &sm.Message
+		signature = &sm.Signature
+	}
+
+	return &ParsedTx{
+		ParsedCall: *r.parseCall(msg.To, msg.Value, msg.Method, msg.Params, actorsOf(msg.To)),
+		From:       r.formatAddress(msg.From),
+		Nonce:      msg.Nonce,
+		GasLimit:   msg.GasLimit,
+		GasFeeCap:  msg.GasFeeCap,
+		GasPremium: msg.GasPremium,
+		Signature:  signature,
+	}, nil
+}
+
+// actorsOf returns the actors that may be at address to, singleton actors are recognized by their address
+// while the others can only be told apart by the params of their methods
+func actorsOf(to address.Address) []string {
+	switch to {
+	case builtin.InitActorAddr:
+		return []string{ActorInit}
+	case builtin.StorageMarketActorAddr:
+		return []string{ActorMarket}
+	case builtin.VerifiedRegistryActorAddr:
+		return []string{ActorVerifreg}
+	}
+
+	// The methods of the other singleton actors are not decoded
+	if isSingleton(to) {
+		return nil
+	}
+	return []string{ActorMultisig, ActorMiner, ActorPaych}
+}
+
+// isSingleton tells whether addr is the address of a singleton actor, such as the init or reward actor
+func isSingleton(addr address.Address) bool {
+	id, err := address.IDFromAddress(addr)
+	return err == nil && id < builtin.FirstNonSingletonActorId
+}
+
+// parseCall describes a method call, its params are decoded by the first of actors knowing the method
+func (r RosettaConstructionFilecoin) parseCall(to address.Address, value abi.TokenAmount, method abi.MethodNum,
+	params []byte, actors []string) *ParsedCall {
+	call := &ParsedCall{
+		To:     r.formatAddress(to),
+		Value:  value,
+		Method: uint64(method),
+	}
+
+	if method == builtin.MethodSend {
+		call.MethodName = "Send"
+		if len(params) != 0 {
+			call.RawParams = params
+		}
+		return call
+	}
+
+	for _, actor := range actors {
+		name, decoded, err := r.decodeParams(actor, to, method, params)
+		if err == nil {
+			call.Actor = actor
+			call.MethodName = name
+			call.Params = decoded
+			return call
+		}
+	}
+
+	call.RawParams = params
+	return call
+}
+
+// decodeParams returns the name of a method of actor and its decoded params,
+// or an error if the method is unknown or its params do not match
+func (r RosettaConstructionFilecoin) decodeParams(actor string, to address.Address, method abi.MethodNum,
+	params []byte) (string, map[string]interface{}, error) {
+	switch actor {
+	case ActorInit:
+		return r.decodeInitParams(method, params)
+	case ActorMultisig:
+		return r.decodeMultisigParams(to, method, params)
+	case ActorMiner:
+		return r.decodeMinerParams(method, params)
+	case ActorMarket:
+		return r.decodeMarketParams(method, params)
+	case ActorPaych:
+		return r.decodePaychParams(method, params)
+	case ActorVerifreg:
+		return r.decodeVerifregParams(method, params)
+	default:
+		return "", nil, fmt.Errorf("unknown actor %s", actor)
+	}
+}
+
+// unmarshalParams decodes params into v, trailing bytes mean that params are not of the type of v
+func unmarshalParams(params []byte, v cbg.CBORUnmarshaler) error {
+	reader := bytes.NewReader(params)
+	err := v.UnmarshalCBOR(reader)
+	if err != nil {
+		return err
+	}
+
+	if reader.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes", reader.Len())
+	}
+	return nil
+}
+
+// noParams checks that a method without params was called without params
+func noParams(params []byte) (map[string]interface{}, error) {
+	if len(params) != 0 {
+		return nil, fmt.Errorf("unexpected params")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (r RosettaConstructionFilecoin) formatAddresses(addrs []address.Address) []string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = r.formatAddress(addr)
+	}
+	return formatted
+}
+
+func (r RosettaConstructionFilecoin) decodeInitParams(method abi.MethodNum, params []byte) (string, map[string]interface{}, error) {
+	if method != builtin.MethodsInit.Exec {
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+
+	var exec init_.ExecParams
+	if err := unmarshalParams(params, &exec); err != nil {
+		return "", nil, err
+	}
+
+	decoded := map[string]interface{}{
+		"code_cid": exec.CodeCID.String(),
+		"code":     builtin.ActorNameByCode(exec.CodeCID),
+	}
+
+	if exec.CodeCID == builtin.MultisigActorCodeID {
+		var constructor multisig.ConstructorParams
+		if err := unmarshalParams(exec.ConstructorParams, &constructor); err == nil {
+			decoded["constructor_params"] = map[string]interface{}{
+				"signers":         r.formatAddresses(constructor.Signers),
+				"threshold":       constructor.NumApprovalsThreshold,
+				"unlock_duration": int64(constructor.UnlockDuration),
+			}
+			return "Exec", decoded, nil
+		}
+	}
+
+	if exec.CodeCID == builtin.PaymentChannelActorCodeID {
+		var constructor paych.ConstructorParams
+		if err := unmarshalParams(exec.ConstructorParams, &constructor); err == nil {
+			decoded["constructor_params"] = map[string]interface{}{
+				"from": r.formatAddress(constructor.From),
+				"to":   r.formatAddress(constructor.To),
+			}
+			return "Exec", decoded, nil
+		}
+	}
+
+	decoded["constructor_params"] = exec.ConstructorParams
+	return "Exec", decoded, nil
+}
+
+func (r RosettaConstructionFilecoin) decodeMultisigParams(msig address.Address, method abi.MethodNum,
+	params []byte) (string, map[string]interface{}, error) {
+	switch method {
+	case builtin.MethodsMultisig.Propose:
+		var propose multisig.ProposeParams
+		if err := unmarshalParams(params, &propose); err != nil {
+			return "", nil, err
+		}
+
+		// A multisig calling itself manages its signers, otherwise the destination is guessed as for a message
+		actors := actorsOf(propose.To)
+		if propose.To == msig {
+			actors = []string{ActorMultisig}
+		}
+
+		proposal := r.parseCall(propose.To, propose.Value, propose.Method, propose.Params, actors)
+		return "Propose", map[string]interface{}{"proposal": proposal}, nil
+
+	case builtin.MethodsMultisig.Approve, builtin.MethodsMultisig.Cancel:
+		var txn multisig.TxnIDParams
+		if err := unmarshalParams(params, &txn); err != nil {
+			return "", nil, err
+		}
+
+		decoded := map[string]interface{}{"txn_id": int64(txn.ID)}
+		if len(txn.ProposalHash) != 0 {
+			decoded["proposal_hash"] = hex.EncodeToString(txn.ProposalHash)
+		}
+
+		if method == builtin.MethodsMultisig.Approve {
+			return "Approve", decoded, nil
+		}
+		return "Cancel", decoded, nil
+
+	case builtin.MethodsMultisig.AddSigner:
+		var add multisig.AddSignerParams
+		if err := unmarshalParams(params, &add); err != nil {
+			return "", nil, err
+		}
+		return "AddSigner", map[string]interface{}{
+			"signer":   r.formatAddress(add.Signer),
+			"increase": add.Increase,
+		}, nil
+
+	case builtin.MethodsMultisig.RemoveSigner:
+		var remove multisig.RemoveSignerParams
+		if err := unmarshalParams(params, &remove); err != nil {
+			return "", nil, err
+		}
+		return "RemoveSigner", map[string]interface{}{
+			"signer":   r.formatAddress(remove.Signer),
+			"decrease": remove.Decrease,
+		}, nil
+
+	case builtin.MethodsMultisig.SwapSigner:
+		var swap multisig.SwapSignerParams
+		if err := unmarshalParams(params, &swap); err != nil {
+			return "", nil, err
+		}
+		return "SwapSigner", map[string]interface{}{
+			"from": r.formatAddress(swap.From),
+			"to":   r.formatAddress(swap.To),
+		}, nil
+
+	case builtin.MethodsMultisig.ChangeNumApprovalsThreshold:
+		var threshold multisig.ChangeNumApprovalsThresholdParams
+		if err := unmarshalParams(params, &threshold); err != nil {
+			return "", nil, err
+		}
+		return "ChangeNumApprovalsThreshold", map[string]interface{}{
+			"new_threshold": threshold.NewThreshold,
+		}, nil
+
+	case builtin.MethodsMultisig.LockBalance:
+		var lock multisig.LockBalanceParams
+		if err := unmarshalParams(params, &lock); err != nil {
+			return "", nil, err
+		}
+		return "LockBalance", map[string]interface{}{
+			"start_epoch":     int64(lock.StartEpoch),
+			"unlock_duration": int64(lock.UnlockDuration),
+			"amount":          lock.Amount,
+		}, nil
+
+	default:
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+}
+
+func (r RosettaConstructionFilecoin) decodeMinerParams(method abi.MethodNum, params []byte) (string, map[string]interface{}, error) {
+	switch method {
+	case builtin.MethodsMiner.ControlAddresses:
+		decoded, err := noParams(params)
+		return "ControlAddresses", decoded, err
+
+	case builtin.MethodsMiner.ChangeWorkerAddress:
+		var change miner.ChangeWorkerAddressParams
+		if err := unmarshalParams(params, &change); err != nil {
+			return "", nil, err
+		}
+		return "ChangeWorkerAddress", map[string]interface{}{
+			"new_worker":        r.formatAddress(change.NewWorker),
+			"new_control_addrs": r.formatAddresses(change.NewControlAddrs),
+		}, nil
+
+	case builtin.MethodsMiner.ChangePeerID:
+		var change miner.ChangePeerIDParams
+		if err := unmarshalParams(params, &change); err != nil {
+			return "", nil, err
+		}
+
+		// Peer IDs are multihashes, shown in base58 like libp2p does
+		peerID, err := multihash.Cast(change.NewID)
+		if err != nil {
+			return "", nil, err
+		}
+		return "ChangePeerID", map[string]interface{}{
+			"new_id": peerID.B58String(),
+		}, nil
+
+	case builtin.MethodsMiner.WithdrawBalance:
+		var withdraw miner.WithdrawBalanceParams
+		if err := unmarshalParams(params, &withdraw); err != nil {
+			return "", nil, err
+		}
+		return "WithdrawBalance", map[string]interface{}{
+			"amount_requested": withdraw.AmountRequested,
+		}, nil
+
+	default:
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+}
+
+func (r RosettaConstructionFilecoin) decodeMarketParams(method abi.MethodNum, params []byte) (string, map[string]interface{}, error) {
+	switch method {
+	case builtin.MethodsMarket.AddBalance:
+		var account address.Address
+		if err := unmarshalParams(params, &account); err != nil {
+			return "", nil, err
+		}
+		return "AddBalance", map[string]interface{}{
+			"provider_or_client": r.formatAddress(account),
+		}, nil
+
+	case builtin.MethodsMarket.WithdrawBalance:
+		var withdraw market.WithdrawBalanceParams
+		if err := unmarshalParams(params, &withdraw); err != nil {
+			return "", nil, err
+		}
+		return "WithdrawBalance", map[string]interface{}{
+			"provider_or_client": r.formatAddress(withdraw.ProviderOrClientAddress),
+			"amount":             withdraw.Amount,
+		}, nil
+
+	default:
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+}
+
+func (r RosettaConstructionFilecoin) decodePaychParams(method abi.MethodNum, params []byte) (string, map[string]interface{}, error) {
+	switch method {
+	case builtin.MethodsPaych.UpdateChannelState:
+		var update paych.UpdateChannelStateParams
+		if err := unmarshalParams(params, &update); err != nil {
+			return "", nil, err
+		}
+
+		sv := update.Sv
+		voucher := map[string]interface{}{
+			"channel":           r.formatAddress(sv.ChannelAddr),
+			"lane":              sv.Lane,
+			"nonce":             sv.Nonce,
+			"amount":            sv.Amount,
+			"time_lock_min":     int64(sv.TimeLockMin),
+			"time_lock_max":     int64(sv.TimeLockMax),
+			"min_settle_height": int64(sv.MinSettleHeight),
+			"signed":            sv.Signature != nil,
+		}
+		if len(sv.SecretPreimage) != 0 {
+			voucher["secret_preimage"] = hex.EncodeToString(sv.SecretPreimage)
+		}
+
+		decoded := map[string]interface{}{"voucher": voucher}
+		if len(update.Secret) != 0 {
+			decoded["secret"] = hex.EncodeToString(update.Secret)
+		}
+		if len(update.Proof) != 0 {
+			decoded["proof"] = hex.EncodeToString(update.Proof)
+		}
+		return "UpdateChannelState", decoded, nil
+
+	case builtin.MethodsPaych.Settle:
+		decoded, err := noParams(params)
+		return "Settle", decoded, err
+
+	case builtin.MethodsPaych.Collect:
+		decoded, err := noParams(params)
+		return "Collect", decoded, err
+
+	default:
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+}
+
+func (r RosettaConstructionFilecoin) decodeVerifregParams(method abi.MethodNum, params []byte) (string, map[string]interface{}, error) {
+	switch method {
+	case builtin.MethodsVerifiedRegistry.AddVerifier:
+		var add verifreg.AddVerifierParams
+		if err := unmarshalParams(params, &add); err != nil {
+			return "", nil, err
+		}
+		return "AddVerifier", map[string]interface{}{
+			"address":   r.formatAddress(add.Address),
+			"allowance": add.Allowance,
+		}, nil
+
+	case builtin.MethodsVerifiedRegistry.RemoveVerifier:
+		var verifier address.Address
+		if err := unmarshalParams(params, &verifier); err != nil {
+			return "", nil, err
+		}
+		return "RemoveVerifier", map[string]interface{}{
+			"address": r.formatAddress(verifier),
+		}, nil
+
+	case builtin.MethodsVerifiedRegistry.AddVerifiedClient:
+		var add verifreg.AddVerifiedClientParams
+		if err := unmarshalParams(params, &add); err != nil {
+			return "", nil, err
+		}
+		return "AddVerifiedClient", map[string]interface{}{
+			"address":   r.formatAddress(add.Address),
+			"allowance": add.Allowance,
+		}, nil
+
+	default:
+		return "", nil, fmt.Errorf("unknown method %d", method)
+	}
+}
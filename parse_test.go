@@ -0,0 +1,116 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/multiformats/go-multihash"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func TestParseTxDetailedMinerAndMarket(t *testing.T) {
+	owner, _ := address.NewFromString("t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba")
+	worker, _ := address.NewFromString("t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy")
+	minerAddr, _ := address.NewFromString("t01000")
+
+	peerID, err := multihash.Sum([]byte("peer"), multihash.IDENTITY, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		to         address.Address
+		method     abi.MethodNum
+		params     cbg.CBORMarshaler
+		actor      string
+		methodName string
+		expected   string
+	}{
+		{minerAddr, builtin.MethodsMiner.ChangeWorkerAddress,
+			&miner.ChangeWorkerAddressParams{NewWorker: worker, NewControlAddrs: []address.Address{owner}},
+			ActorMiner, "ChangeWorkerAddress",
+			`{"new_control_addrs":["` + owner.String() + `"],"new_worker":"` + worker.String() + `"}`},
+		{minerAddr, builtin.MethodsMiner.ChangePeerID, &miner.ChangePeerIDParams{NewID: abi.PeerID(peerID)},
+			ActorMiner, "ChangePeerID", `{"new_id":"` + peerID.B58String() + `"}`},
+		{minerAddr, builtin.MethodsMiner.WithdrawBalance, &miner.WithdrawBalanceParams{AmountRequested: abi.NewTokenAmount(42)},
+			ActorMiner, "WithdrawBalance", `{"amount_requested":"42"}`},
+		{builtin.StorageMarketActorAddr, builtin.MethodsMarket.AddBalance, &owner,
+			ActorMarket, "AddBalance", `{"provider_or_client":"` + owner.String() + `"}`},
+		{builtin.StorageMarketActorAddr, builtin.MethodsMarket.WithdrawBalance,
+			&market.WithdrawBalanceParams{ProviderOrClientAddress: owner, Amount: abi.NewTokenAmount(7)},
+			ActorMarket, "WithdrawBalance", `{"amount":"7","provider_or_client":"` + owner.String() + `"}`},
+	}
+
+	r := &RosettaConstructionFilecoin{Mainnet: false}
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		if err := c.params.MarshalCBOR(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := json.Marshal(&types.Message{
+			To:         c.to,
+			From:       owner,
+			Value:      abi.NewTokenAmount(0),
+			GasFeeCap:  abi.NewTokenAmount(1),
+			GasPremium: abi.NewTokenAmount(1),
+			Method:     c.method,
+			Params:     buf.Bytes(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := r.ParseTxDetailed(string(tx))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		params, err := json.Marshal(parsed.Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.Actor != c.actor || parsed.MethodName != c.methodName || string(params) != c.expected {
+			t.Errorf("unexpected parsed %s call of %s: %s", parsed.MethodName, parsed.Actor, params)
+		}
+	}
+}
+
+func TestParseTxDetailedMainnet(t *testing.T) {
+	r := &RosettaConstructionFilecoin{Mainnet: true}
+	tx := `{"To":"t01002","From":"t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","Nonce":1,"Value":"0","GasLimit":25000,` +
+		`"GasFeeCap":"1","GasPremium":"1","Method":2,"Params":"hEMA6gdAB1gtglUB3+SRhNRq3I+J1EY4vrRfePytJZBVAeQ8w10L4iTPA9V+iE3/tipwhzV8"}`
+
+	parsed, err := r.ParseTxDetailed(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposal, ok := parsed.Params["proposal"].(*ParsedCall)
+	if !ok || parsed.To != "f01002" || parsed.From[0] != 'f' || proposal.MethodName != "SwapSigner" || proposal.Params["to"].(string)[0] != 'f' {
+		t.Errorf("addresses should be formatted for mainnet: %+v %+v", parsed, proposal)
+	}
+}
@@ -0,0 +1,215 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+)
+
+// EncodeVoucher encodes a voucher like Lotus does, as base64url (unpadded) CBOR
+func EncodeVoucher(sv *paych.SignedVoucher) (string, error) {
+	buf := new(bytes.Buffer)
+	err := sv.MarshalCBOR(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeVoucher decodes a voucher encoded by EncodeVoucher or by Lotus
+func DecodeVoucher(voucher string) (*paych.SignedVoucher, error) {
+	b, err := base64.RawURLEncoding.DecodeString(voucher)
+	if err != nil {
+		return nil, fmt.Errorf("voucher is not base64url: %v", err)
+	}
+
+	var sv paych.SignedVoucher
+	err = unmarshalParams(b, &sv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid voucher: %v", err)
+	}
+
+	return &sv, nil
+}
+
+func (r RosettaConstructionFilecoin) ConstructPaychCreate(request *PaychCreateRequest) (string, error) {
+	from, err := r.parseAddress(request.From)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := r.parseAddress(request.To)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := validateAmount("quantity", request.Quantity)
+	if err != nil {
+		return "", err
+	}
+
+	constructorParams := &paych.ConstructorParams{
+		From: from,
+		To:   to,
+	}
+
+	bufConstructor := new(bytes.Buffer)
+	err = constructorParams.MarshalCBOR(bufConstructor)
+	if err != nil {
+		return "", err
+	}
+
+	execParams := &init_.ExecParams{
+		CodeCID:           builtin.PaymentChannelActorCodeID,
+		ConstructorParams: bufConstructor.Bytes(),
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.InitActorAddr), request.From, "", value, &request.Metadata,
+		builtin.MethodsInit.Exec, execParams)
+}
+
+func (r RosettaConstructionFilecoin) CreateVoucher(request *VoucherRequest) (string, error) {
+	channel, err := r.parseAddress(request.Channel)
+	if err != nil {
+		return "", err
+	}
+
+	amount, err := validateAmount("amount", request.Amount)
+	if err != nil {
+		return "", err
+	}
+
+	if request.TimeLockMin < 0 || request.TimeLockMax < 0 || request.MinSettleHeight < 0 {
+		return "", fmt.Errorf("epochs cannot be negative")
+	}
+
+	if request.TimeLockMax != 0 && request.TimeLockMax < request.TimeLockMin {
+		return "", fmt.Errorf("time lock max %d is before time lock min %d", request.TimeLockMax, request.TimeLockMin)
+	}
+
+	if request.Nonce == 0 {
+		return "", fmt.Errorf("voucher nonce must be positive, the nonce of a new lane is 0")
+	}
+
+	if len(request.SecretPreimage) != 0 && len(request.SecretPreimage) != 32 {
+		return "", fmt.Errorf("secret preimage must be a 32 bytes blake2b-256 hash")
+	}
+
+	sv := &paych.SignedVoucher{
+		ChannelAddr:     channel,
+		TimeLockMin:     abi.ChainEpoch(request.TimeLockMin),
+		TimeLockMax:     abi.ChainEpoch(request.TimeLockMax),
+		SecretPreimage:  request.SecretPreimage,
+		Lane:            request.Lane,
+		Nonce:           request.Nonce,
+		Amount:          amount,
+		MinSettleHeight: abi.ChainEpoch(request.MinSettleHeight),
+	}
+
+	return EncodeVoucher(sv)
+}
+
+func (r RosettaConstructionFilecoin) SignVoucher(voucher string, sk []byte, sigType crypto.SigType) (string, error) {
+	sv, err := DecodeVoucher(voucher)
+	if err != nil {
+		return "", err
+	}
+
+	vb, err := sv.SigningBytes()
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := r.SignWithType(vb, sk, sigType)
+	if err != nil {
+		return "", err
+	}
+
+	sv.Signature = &crypto.Signature{Type: sigType, Data: sig}
+	return EncodeVoucher(sv)
+}
+
+func (r RosettaConstructionFilecoin) VerifyVoucher(voucher string, from string) error {
+	sv, err := DecodeVoucher(voucher)
+	if err != nil {
+		return err
+	}
+
+	if sv.Signature == nil {
+		return fmt.Errorf("voucher is not signed")
+	}
+
+	signer, err := r.parseAddress(from)
+	if err != nil {
+		return err
+	}
+
+	sigType, err := signatureType(signer)
+	if err != nil {
+		return err
+	}
+
+	if sv.Signature.Type != sigType {
+		return fmt.Errorf("voucher signature type %d does not match the address %s", sv.Signature.Type, from)
+	}
+
+	vb, err := sv.SigningBytes()
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(sv.Signature, signer, vb)
+}
+
+func (r RosettaConstructionFilecoin) ConstructPaychUpdateChannelState(request *PaychUpdateChannelStateRequest) (string, error) {
+	sv, err := DecodeVoucher(request.Params.Voucher)
+	if err != nil {
+		return "", err
+	}
+
+	if sv.Signature == nil {
+		return "", fmt.Errorf("voucher is not signed")
+	}
+
+	updateParams := &paych.UpdateChannelStateParams{
+		Sv:     *sv,
+		Secret: request.Params.Secret,
+		Proof:  request.Params.Proof,
+	}
+
+	return r.constructActorCall(r.formatAddress(sv.ChannelAddr), request.From, "", big.Zero(), &request.Metadata,
+		builtin.MethodsPaych.UpdateChannelState, updateParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructPaychSettle(request *PaychRequest) (string, error) {
+	return r.constructActorCall(request.Channel, request.From, "", big.Zero(), &request.Metadata,
+		builtin.MethodsPaych.Settle, nil)
+}
+
+func (r RosettaConstructionFilecoin) ConstructPaychCollect(request *PaychRequest) (string, error) {
+	return r.constructActorCall(request.Channel, request.From, "", big.Zero(), &request.Metadata,
+		builtin.MethodsPaych.Collect, nil)
+}
@@ -0,0 +1,117 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+)
+
+// Rosetta statuses of the operations of an executed multisig Propose or Approve message
+const (
+	// OperationStatusOk is the status of a proposal applied successfully
+	OperationStatusOk = "Ok"
+	// OperationStatusPending is the status of a proposal waiting for more approvals
+	OperationStatusPending = "Pending"
+	// OperationStatusFailed is the status of a proposal applied with a non zero exit code
+	OperationStatusFailed = "Failed"
+)
+
+// ProposeResult is the outcome of a multisig Propose message
+type ProposeResult struct {
+	// TxnID identifies the pending transaction, it is the ID to approve or cancel while the proposal is not applied
+	TxnID int64 `json:"txn_id"`
+	// Applied is true when the proposal met the threshold and was executed
+	Applied bool `json:"applied"`
+	// ExitCode and Return are the result of the proposed call when Applied
+	ExitCode exitcode.ExitCode `json:"exit_code"`
+	Return   []byte            `json:"return,omitempty"`
+}
+
+// Status returns the Rosetta status of the proposal
+func (r *ProposeResult) Status() string {
+	return multisigStatus(r.Applied, r.ExitCode)
+}
+
+// ApproveResult is the outcome of a multisig Approve message
+type ApproveResult struct {
+	// Applied is true when the approval met the threshold and the transaction was executed
+	Applied bool `json:"applied"`
+	// ExitCode and Return are the result of the proposed call when Applied
+	ExitCode exitcode.ExitCode `json:"exit_code"`
+	Return   []byte            `json:"return,omitempty"`
+}
+
+// Status returns the Rosetta status of the approved proposal
+func (r *ApproveResult) Status() string {
+	return multisigStatus(r.Applied, r.ExitCode)
+}
+
+func multisigStatus(applied bool, code exitcode.ExitCode) string {
+	switch {
+	case !applied:
+		return OperationStatusPending
+	case code.IsSuccess():
+		return OperationStatusOk
+	default:
+		return OperationStatusFailed
+	}
+}
+
+// DecodeProposeReceipt decodes the receipt of a multisig Propose message, as returned by Lotus StateWaitMsg.
+// A message that failed did not record any proposal, its exit code is returned as an error.
+func DecodeProposeReceipt(receipt *types.MessageReceipt) (*ProposeResult, error) {
+	if !receipt.ExitCode.IsSuccess() {
+		return nil, fmt.Errorf("proposal failed with exit code %d", receipt.ExitCode)
+	}
+
+	var ret multisig.ProposeReturn
+	err := ret.UnmarshalCBOR(bytes.NewReader(receipt.Return))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Propose return: %v", err)
+	}
+
+	return &ProposeResult{
+		TxnID:    int64(ret.TxnID),
+		Applied:  ret.Applied,
+		ExitCode: ret.Code,
+		Return:   ret.Ret,
+	}, nil
+}
+
+// DecodeApproveReceipt decodes the receipt of a multisig Approve message, as returned by Lotus StateWaitMsg.
+// A message that failed did not record any approval, its exit code is returned as an error.
+func DecodeApproveReceipt(receipt *types.MessageReceipt) (*ApproveResult, error) {
+	if !receipt.ExitCode.IsSuccess() {
+		return nil, fmt.Errorf("approval failed with exit code %d", receipt.ExitCode)
+	}
+
+	var ret multisig.ApproveReturn
+	err := ret.UnmarshalCBOR(bytes.NewReader(receipt.Return))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Approve return: %v", err)
+	}
+
+	return &ApproveResult{
+		Applied:  ret.Applied,
+		ExitCode: ret.Code,
+		Return:   ret.Ret,
+	}, nil
+}
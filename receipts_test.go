@@ -0,0 +1,87 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+func receipt(t *testing.T, ret cbg.CBORMarshaler) *types.MessageReceipt {
+	buf := new(bytes.Buffer)
+	if err := ret.MarshalCBOR(buf); err != nil {
+		t.Fatal(err)
+	}
+	return &types.MessageReceipt{ExitCode: exitcode.Ok, Return: buf.Bytes()}
+}
+
+func TestDecodeProposeReceipt(t *testing.T) {
+	cases := []struct {
+		ret    multisig.ProposeReturn
+		status string
+	}{
+		{multisig.ProposeReturn{TxnID: 4}, OperationStatusPending},
+		{multisig.ProposeReturn{TxnID: 5, Applied: true, Ret: []byte{}}, OperationStatusOk},
+		{multisig.ProposeReturn{TxnID: 6, Applied: true, Code: exitcode.ErrInsufficientFunds, Ret: []byte{}}, OperationStatusFailed},
+	}
+
+	for _, c := range cases {
+		result, err := DecodeProposeReceipt(receipt(t, &c.ret))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.TxnID != int64(c.ret.TxnID) || result.Applied != c.ret.Applied || result.ExitCode != c.ret.Code {
+			t.Errorf("unexpected result %+v for %+v", result, c.ret)
+		}
+		if result.Status() != c.status {
+			t.Errorf("expected status %s, got %s", c.status, result.Status())
+		}
+	}
+
+	if _, err := DecodeProposeReceipt(&types.MessageReceipt{ExitCode: exitcode.ErrForbidden}); err == nil {
+		t.Error("a failed message should not be decoded")
+	}
+
+	if _, err := DecodeProposeReceipt(&types.MessageReceipt{ExitCode: exitcode.Ok, Return: []byte{1}}); err == nil {
+		t.Error("an invalid return should not be decoded")
+	}
+}
+
+func TestDecodeApproveReceipt(t *testing.T) {
+	result, err := DecodeApproveReceipt(receipt(t, &multisig.ApproveReturn{Applied: true, Ret: []byte{1, 2}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Applied || !bytes.Equal(result.Return, []byte{1, 2}) || result.Status() != OperationStatusOk {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result, err = DecodeApproveReceipt(receipt(t, &multisig.ApproveReturn{Ret: []byte{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status() != OperationStatusPending {
+		t.Errorf("unexpected status %s", result.Status())
+	}
+}
@@ -0,0 +1,49 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package server
+
+import "fmt"
+
+var (
+	ErrInvalidRequest        = &Error{Code: 1, Message: "invalid request"}
+	ErrInvalidNetwork        = &Error{Code: 2, Message: "invalid network"}
+	ErrUnsupportedOperations = &Error{Code: 3, Message: "unsupported operations"}
+	ErrConstruction          = &Error{Code: 4, Message: "unable to construct transaction"}
+	ErrOffline               = &Error{Code: 5, Message: "endpoint not available in offline mode"}
+	ErrNode                  = &Error{Code: 6, Message: "node request failed", Retriable: true}
+)
+
+// Errors lists every error the server can return, as expected by /network/options
+var Errors = []*Error{
+	ErrInvalidRequest,
+	ErrInvalidNetwork,
+	ErrUnsupportedOperations,
+	ErrConstruction,
+	ErrOffline,
+	ErrNode,
+}
+
+// wrapError returns a copy of a predefined error carrying the cause in its details
+func wrapError(template *Error, cause interface{}) *Error {
+	return &Error{
+		Code:      template.Code,
+		Message:   template.Message,
+		Retriable: template.Retriable,
+		Details: map[string]interface{}{
+			"error": fmt.Sprint(cause),
+		},
+	}
+}
@@ -0,0 +1,105 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package server
+
+import (
+	"github.com/filecoin-project/go-state-types/big"
+
+	rosettaFilecoinLib "github.com/zondax/rosetta-filecoin-lib"
+)
+
+// Operation types, see rosettaFilecoinLib for their accounts, amounts and metadata
+const (
+	OperationSend                        = rosettaFilecoinLib.OperationSend
+	OperationPropose                     = rosettaFilecoinLib.OperationPropose
+	OperationSwapSigner                  = rosettaFilecoinLib.OperationSwapSigner
+	OperationAddSigner                   = rosettaFilecoinLib.OperationAddSigner
+	OperationRemoveSigner                = rosettaFilecoinLib.OperationRemoveSigner
+	OperationChangeNumApprovalsThreshold = rosettaFilecoinLib.OperationChangeNumApprovalsThreshold
+	OperationLockBalance                 = rosettaFilecoinLib.OperationLockBalance
+	OperationCreateMultisig              = rosettaFilecoinLib.OperationCreateMultisig
+	OperationApprove                     = rosettaFilecoinLib.OperationApprove
+	OperationCancel                      = rosettaFilecoinLib.OperationCancel
+	OperationMarketAddBalance            = rosettaFilecoinLib.OperationMarketAddBalance
+	OperationMarketWithdrawBalance       = rosettaFilecoinLib.OperationMarketWithdrawBalance
+)
+
+// toLibraryOperations converts Rosetta operations into the operations of the library
+func toLibraryOperations(operations []*Operation) ([]rosettaFilecoinLib.Operation, *Error) {
+	result := make([]rosettaFilecoinLib.Operation, 0, len(operations))
+	for _, op := range operations {
+		if op.Account == nil {
+			return nil, wrapError(ErrUnsupportedOperations, "expected operations with an account")
+		}
+
+		amount := big.Zero()
+		if op.Amount != nil {
+			if op.Amount.Currency != FIL {
+				return nil, wrapError(ErrUnsupportedOperations, "unsupported currency "+op.Amount.Currency.Symbol)
+			}
+
+			var err error
+			amount, err = big.FromString(op.Amount.Value)
+			if err != nil {
+				return nil, wrapError(ErrInvalidRequest, err)
+			}
+		}
+
+		var metadata map[string]string
+		err := convert(op.Metadata, &metadata)
+		if err != nil {
+			return nil, wrapError(ErrInvalidRequest, err)
+		}
+
+		result = append(result, rosettaFilecoinLib.Operation{
+			Type:     op.Type,
+			Account:  op.Account.Address,
+			Amount:   amount,
+			Metadata: metadata,
+		})
+	}
+
+	return result, nil
+}
+
+// fromLibraryOperations converts the operations of the library into Rosetta operations.
+// The receiver of a transfer is related to its sender.
+func fromLibraryOperations(operations []rosettaFilecoinLib.Operation) ([]*Operation, *Error) {
+	result := make([]*Operation, 0, len(operations))
+	for i, op := range operations {
+		operation := &Operation{
+			OperationIdentifier: OperationIdentifier{Index: int64(i)},
+			Type:                op.Type,
+			Account:             &AccountIdentifier{Address: op.Account},
+			Amount:              &Amount{Value: op.Amount.String(), Currency: FIL},
+		}
+
+		if op.Type == OperationSend && i > 0 && operations[i-1].Type == OperationSend {
+			operation.RelatedOperations = []OperationIdentifier{{Index: int64(i - 1)}}
+		}
+
+		if op.Metadata != nil {
+			err := convert(op.Metadata, &operation.Metadata)
+			if err != nil {
+				return nil, wrapError(ErrConstruction, err)
+			}
+		}
+
+		result = append(result, operation)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,465 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+
+// Package server exposes the Rosetta Construction API on top of rosettaFilecoinLib.
+// The offline endpoints (derive, preprocess, payloads, parse, combine, hash) only use the library,
+// so they can run air-gapped. The online endpoints (metadata, submit) delegate to a NodeClient.
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/minio/blake2b-simd"
+
+	rosettaFilecoinLib "github.com/zondax/rosetta-filecoin-lib"
+	"github.com/zondax/rosetta-filecoin-lib/lotusclient"
+)
+
+const (
+	Blockchain = "Filecoin"
+	Mainnet    = "mainnet"
+	Testnet    = "testnet"
+
+	CurveSecp256k1 = "secp256k1"
+	// CurveBLS12381 is not part of the Rosetta specification, it is used for Filecoin BLS keys (G1 public keys)
+	CurveBLS12381 = "bls12381"
+
+	SignatureEcdsaRecovery = "ecdsa_recovery"
+	// SignatureBLS12381 is not part of the Rosetta specification, it is used for Filecoin BLS signatures
+	SignatureBLS12381 = "bls12381"
+
+	// maxRequestSize limits the size of request bodies
+	maxRequestSize = 1 << 20
+)
+
+// FIL is the currency of every amount
+var FIL = Currency{Symbol: "FIL", Decimals: 18}
+
+// NodeClient gives the online endpoints access to a Filecoin node. Its methods mirror the Lotus API.
+type NodeClient interface {
+	// MpoolGetNonce returns the next nonce of an account, taking pending messages into account
+	MpoolGetNonce(ctx context.Context, addr address.Address) (uint64, error)
+	// GasEstimateMessageGas returns msg with its gas limit, fee cap and premium estimated
+	GasEstimateMessageGas(ctx context.Context, msg *types.Message) (*types.Message, error)
+	// MpoolPush submits a signed message and returns its cid
+	MpoolPush(ctx context.Context, sm *types.SignedMessage) (cid.Cid, error)
+}
+
+// lotusclient.Client connects the server to a Lotus node
+var _ NodeClient = (*lotusclient.Client)(nil)
+
+// Server serves the Rosetta Construction API
+type Server struct {
+	tool    rosettaFilecoinLib.RosettaConstructionTool
+	mainnet bool
+	client  NodeClient
+}
+
+// NewServer creates a server backed by tool, serving mainnet or testnet which must be the network tool is
+// configured for. client may be nil, in which case the server runs offline and the online endpoints return
+// ErrOffline.
+func NewServer(tool rosettaFilecoinLib.RosettaConstructionTool, mainnet bool, client NodeClient) *Server {
+	return &Server{
+		tool:    tool,
+		mainnet: mainnet,
+		client:  client,
+	}
+}
+
+// Handler returns the http.Handler serving every construction endpoint
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/construction/derive", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionDeriveRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.derive(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/preprocess", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionPreprocessRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.preprocess(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/metadata", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionMetadataRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.metadata(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/payloads", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionPayloadsRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.payloads(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/parse", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionParseRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.parse(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/combine", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionCombineRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.combine(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/hash", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionHashRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.hash(ctx, &req)
+	}))
+
+	mux.HandleFunc("/construction/submit", s.handle(func(ctx context.Context, body []byte) (interface{}, *Error) {
+		var req ConstructionSubmitRequest
+		if err := s.decode(body, &req, &req.NetworkIdentifier); err != nil {
+			return nil, err
+		}
+		return s.submit(ctx, &req)
+	}))
+
+	return mux
+}
+
+// handle wraps an endpoint: Rosetta endpoints are POST requests with JSON bodies,
+// replying with the response or an Error and status 500
+func (s *Server) handle(endpoint func(ctx context.Context, body []byte) (interface{}, *Error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = json.NewEncoder(w).Encode(wrapError(ErrInvalidRequest, "method must be POST"))
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(wrapError(ErrInvalidRequest, err))
+			return
+		}
+
+		resp, rosettaErr := endpoint(r.Context(), body)
+		if rosettaErr != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(rosettaErr)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// decode unmarshals a request and checks it targets the network of the server
+func (s *Server) decode(body []byte, req interface{}, network **NetworkIdentifier) *Error {
+	err := json.Unmarshal(body, req)
+	if err != nil {
+		return wrapError(ErrInvalidRequest, err)
+	}
+
+	return s.checkNetwork(*network)
+}
+
+func (s *Server) networkName() string {
+	if s.mainnet {
+		return Mainnet
+	}
+	return Testnet
+}
+
+func (s *Server) checkNetwork(network *NetworkIdentifier) *Error {
+	if network == nil {
+		return wrapError(ErrInvalidNetwork, "missing network_identifier")
+	}
+
+	if network.Blockchain != Blockchain || network.Network != s.networkName() {
+		return wrapError(ErrInvalidNetwork, "expected "+Blockchain+" "+s.networkName())
+	}
+
+	return nil
+}
+
+// convert copies a value into another type through its JSON representation, used for metadata and options maps
+func convert(in interface{}, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (s *Server) derive(_ context.Context, req *ConstructionDeriveRequest) (*ConstructionDeriveResponse, *Error) {
+	if req.PublicKey == nil {
+		return nil, wrapError(ErrInvalidRequest, "missing public_key")
+	}
+
+	pk, err := hex.DecodeString(req.PublicKey.HexBytes)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	switch req.PublicKey.CurveType {
+	case CurveSecp256k1:
+		if len(pk) == address.BlsPublicKeyBytes {
+			return nil, wrapError(ErrInvalidRequest, "invalid secp256k1 public key")
+		}
+	case CurveBLS12381:
+		if len(pk) != address.BlsPublicKeyBytes {
+			return nil, wrapError(ErrInvalidRequest, "invalid BLS public key")
+		}
+	default:
+		return nil, wrapError(ErrInvalidRequest, "unsupported curve "+req.PublicKey.CurveType)
+	}
+
+	addr, err := s.tool.DeriveFromPublicKey(pk)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	return &ConstructionDeriveResponse{
+		AccountIdentifier: &AccountIdentifier{Address: addr},
+	}, nil
+}
+
+// preprocessOptions are produced by /preprocess and consumed by /metadata
+type preprocessOptions struct {
+	// Transaction is the unsigned transaction described by the operations, without nonce nor gas
+	Transaction string `json:"transaction"`
+}
+
+func (s *Server) preprocess(_ context.Context, req *ConstructionPreprocessRequest) (*ConstructionPreprocessResponse, *Error) {
+	operations, rosettaErr := toLibraryOperations(req.Operations)
+	if rosettaErr != nil {
+		return nil, rosettaErr
+	}
+
+	unsignedTx, err := s.tool.ConstructFromOperations(operations, rosettaFilecoinLib.TxMetadata{})
+	if err != nil {
+		return nil, wrapError(ErrUnsupportedOperations, err)
+	}
+
+	var options map[string]interface{}
+	err = convert(&preprocessOptions{Transaction: unsignedTx}, &options)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	return &ConstructionPreprocessResponse{Options: options}, nil
+}
+
+func (s *Server) metadata(ctx context.Context, req *ConstructionMetadataRequest) (*ConstructionMetadataResponse, *Error) {
+	if s.client == nil {
+		return nil, ErrOffline
+	}
+
+	var options preprocessOptions
+	err := convert(req.Options, &options)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	msg, _, err := rosettaFilecoinLib.DecodeTx(options.Transaction)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	if msg == nil {
+		return nil, wrapError(ErrInvalidRequest, "expected an unsigned transaction")
+	}
+
+	nonce, err := s.client.MpoolGetNonce(ctx, msg.From)
+	if err != nil {
+		return nil, wrapError(ErrNode, err)
+	}
+
+	msg.Nonce = nonce
+	msg, err = s.client.GasEstimateMessageGas(ctx, msg)
+	if err != nil {
+		return nil, wrapError(ErrNode, err)
+	}
+
+	var metadata map[string]interface{}
+	err = convert(&rosettaFilecoinLib.TxMetadata{
+		Nonce:      nonce,
+		GasFeeCap:  msg.GasFeeCap,
+		GasPremium: msg.GasPremium,
+		GasLimit:   msg.GasLimit,
+	}, &metadata)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	fee := big.Mul(msg.GasFeeCap, big.NewInt(msg.GasLimit))
+	return &ConstructionMetadataResponse{
+		Metadata:     metadata,
+		SuggestedFee: []*Amount{{Value: fee.String(), Currency: FIL}},
+	}, nil
+}
+
+func (s *Server) payloads(_ context.Context, req *ConstructionPayloadsRequest) (*ConstructionPayloadsResponse, *Error) {
+	operations, rosettaErr := toLibraryOperations(req.Operations)
+	if rosettaErr != nil {
+		return nil, rosettaErr
+	}
+
+	var metadata rosettaFilecoinLib.TxMetadata
+	err := convert(req.Metadata, &metadata)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	unsignedTx, err := s.tool.ConstructFromOperations(operations, metadata)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	payload, err := s.tool.ConstructSigningPayload(unsignedTx)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	// The first operation is always the account signing the transaction
+	signingPayload := &SigningPayload{
+		AccountIdentifier: &AccountIdentifier{Address: operations[0].Account},
+	}
+
+	switch payload.SignatureType {
+	case crypto.SigTypeSecp256k1:
+		// Rosetta signers sign the 32 bytes they are given, secp256k1 signatures are computed over the blake2b hash
+		digest := blake2b.Sum256(payload.Bytes)
+		signingPayload.HexBytes = hex.EncodeToString(digest[:])
+		signingPayload.SignatureType = SignatureEcdsaRecovery
+	case crypto.SigTypeBLS:
+		signingPayload.HexBytes = hex.EncodeToString(payload.Bytes)
+		signingPayload.SignatureType = SignatureBLS12381
+	}
+
+	return &ConstructionPayloadsResponse{
+		UnsignedTransaction: unsignedTx,
+		Payloads:            []*SigningPayload{signingPayload},
+	}, nil
+}
+
+func (s *Server) parse(_ context.Context, req *ConstructionParseRequest) (*ConstructionParseResponse, *Error) {
+	msg, sm, err := rosettaFilecoinLib.DecodeTx(req.Transaction)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	if req.Signed != (sm != nil) {
+		return nil, wrapError(ErrInvalidRequest, "transaction signature status does not match the signed flag")
+	}
+
+	var signers []*AccountIdentifier
+	if sm != nil {
+		msg = &sm.Message
+		signers = []*AccountIdentifier{{Address: rosettaFilecoinLib.FormatAddress(msg.From, s.mainnet)}}
+	}
+
+	parsed, err := s.tool.ParseToOperations(req.Transaction)
+	if err != nil {
+		return nil, wrapError(ErrUnsupportedOperations, err)
+	}
+
+	operations, rosettaErr := fromLibraryOperations(parsed)
+	if rosettaErr != nil {
+		return nil, rosettaErr
+	}
+
+	return &ConstructionParseResponse{
+		Operations:               operations,
+		AccountIdentifierSigners: signers,
+	}, nil
+}
+
+func (s *Server) combine(_ context.Context, req *ConstructionCombineRequest) (*ConstructionCombineResponse, *Error) {
+	if len(req.Signatures) != 1 {
+		return nil, wrapError(ErrInvalidRequest, "expected exactly one signature")
+	}
+
+	sig, err := hex.DecodeString(req.Signatures[0].HexBytes)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	signedTx, err := s.tool.CombineTx(req.UnsignedTransaction, sig)
+	if err != nil {
+		return nil, wrapError(ErrConstruction, err)
+	}
+
+	return &ConstructionCombineResponse{SignedTransaction: signedTx}, nil
+}
+
+func (s *Server) hash(_ context.Context, req *ConstructionHashRequest) (*TransactionIdentifierResponse, *Error) {
+	hash, err := s.tool.Hash(req.SignedTransaction)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	return &TransactionIdentifierResponse{
+		TransactionIdentifier: &TransactionIdentifier{Hash: hash},
+	}, nil
+}
+
+func (s *Server) submit(ctx context.Context, req *ConstructionSubmitRequest) (*TransactionIdentifierResponse, *Error) {
+	if s.client == nil {
+		return nil, ErrOffline
+	}
+
+	_, sm, err := rosettaFilecoinLib.DecodeTx(req.SignedTransaction)
+	if err != nil {
+		return nil, wrapError(ErrInvalidRequest, err)
+	}
+
+	if sm == nil {
+		return nil, wrapError(ErrInvalidRequest, "expected a signed transaction")
+	}
+
+	c, err := s.client.MpoolPush(ctx, sm)
+	if err != nil {
+		return nil, wrapError(ErrNode, err)
+	}
+
+	return &TransactionIdentifierResponse{
+		TransactionIdentifier: &TransactionIdentifier{Hash: c.String()},
+	}, nil
+}
@@ -0,0 +1,406 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	c "github.com/filecoin-project/go-crypto"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+
+	rosettaFilecoinLib "github.com/zondax/rosetta-filecoin-lib"
+)
+
+const (
+	testSecretKey = "f15716d3b003b304b8055d9cc62e6b9c869d56cc930c3858d4d7c31f5f53f14a"
+	testPublicKey = "0435e752dc6b4113f78edcf2cf7b8082e442021de5f00818f555397a6f181af795ace98f0f7d065793eaffa1b06bf52e572c97030c53a2396dfab40ba0e976b108"
+	testFrom      = "t1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+	testTo        = "t17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy"
+)
+
+var testNetwork = &NetworkIdentifier{Blockchain: Blockchain, Network: Testnet}
+
+type fakeNode struct {
+	pushed []*types.SignedMessage
+}
+
+func (n *fakeNode) MpoolGetNonce(_ context.Context, _ address.Address) (uint64, error) {
+	return 7, nil
+}
+
+func (n *fakeNode) GasEstimateMessageGas(_ context.Context, msg *types.Message) (*types.Message, error) {
+	estimated := *msg
+	estimated.GasLimit = 1000
+	estimated.GasFeeCap = abi.NewTokenAmount(100)
+	estimated.GasPremium = abi.NewTokenAmount(10)
+	return &estimated, nil
+}
+
+func (n *fakeNode) MpoolPush(_ context.Context, sm *types.SignedMessage) (cid.Cid, error) {
+	n.pushed = append(n.pushed, sm)
+	return sm.Cid(), nil
+}
+
+func post(t *testing.T, srv *httptest.Server, path string, req interface{}, resp interface{}) *Error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		var rosettaErr Error
+		if err := json.NewDecoder(r.Body).Decode(&rosettaErr); err != nil {
+			t.Fatal(err)
+		}
+		return &rosettaErr
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func transferOperations() []*Operation {
+	return []*Operation{
+		{
+			OperationIdentifier: OperationIdentifier{Index: 0},
+			Type:                OperationSend,
+			Account:             &AccountIdentifier{Address: testFrom},
+			Amount:              &Amount{Value: "-5000000000000000000000", Currency: FIL},
+		},
+		{
+			OperationIdentifier: OperationIdentifier{Index: 1},
+			RelatedOperations:   []OperationIdentifier{{Index: 0}},
+			Type:                OperationSend,
+			Account:             &AccountIdentifier{Address: testTo},
+			Amount:              &Amount{Value: "5000000000000000000000", Currency: FIL},
+		},
+	}
+}
+
+func TestConstructionFlow(t *testing.T) {
+	node := &fakeNode{}
+	srv := httptest.NewServer(NewServer(rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false}, false, node).Handler())
+	defer srv.Close()
+
+	var derive ConstructionDeriveResponse
+	if err := post(t, srv, "/construction/derive", &ConstructionDeriveRequest{
+		NetworkIdentifier: testNetwork,
+		PublicKey:         &PublicKey{HexBytes: testPublicKey, CurveType: CurveSecp256k1},
+	}, &derive); err != nil {
+		t.Fatal(err.Details)
+	}
+	if derive.AccountIdentifier.Address != testFrom {
+		t.Errorf("unexpected address %s", derive.AccountIdentifier.Address)
+	}
+
+	var preprocess ConstructionPreprocessResponse
+	if err := post(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+	}, &preprocess); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var metadata ConstructionMetadataResponse
+	if err := post(t, srv, "/construction/metadata", &ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options:           preprocess.Options,
+	}, &metadata); err != nil {
+		t.Fatal(err.Details)
+	}
+	if metadata.SuggestedFee[0].Value != "100000" {
+		t.Errorf("unexpected suggested fee %s", metadata.SuggestedFee[0].Value)
+	}
+
+	var payloads ConstructionPayloadsResponse
+	if err := post(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+		Metadata:          metadata.Metadata,
+	}, &payloads); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var parsed ConstructionParseResponse
+	if err := post(t, srv, "/construction/parse", &ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Signed:            false,
+		Transaction:       payloads.UnsignedTransaction,
+	}, &parsed); err != nil {
+		t.Fatal(err.Details)
+	}
+	if !reflect.DeepEqual(parsed.Operations, transferOperations()) {
+		t.Errorf("parsed operations differ from the input")
+	}
+
+	// Offline signer: signs the 32 bytes of the payload
+	sk, _ := hex.DecodeString(testSecretKey)
+	digest, _ := hex.DecodeString(payloads.Payloads[0].HexBytes)
+	sig, err := c.Sign(sk, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var combine ConstructionCombineResponse
+	if err := post(t, srv, "/construction/combine", &ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*Signature{{
+			SigningPayload: payloads.Payloads[0],
+			PublicKey:      &PublicKey{HexBytes: testPublicKey, CurveType: CurveSecp256k1},
+			SignatureType:  SignatureEcdsaRecovery,
+			HexBytes:       hex.EncodeToString(sig),
+		}},
+	}, &combine); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var signedParsed ConstructionParseResponse
+	if err := post(t, srv, "/construction/parse", &ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Signed:            true,
+		Transaction:       combine.SignedTransaction,
+	}, &signedParsed); err != nil {
+		t.Fatal(err.Details)
+	}
+	if len(signedParsed.AccountIdentifierSigners) != 1 || signedParsed.AccountIdentifierSigners[0].Address != testFrom {
+		t.Errorf("unexpected signers %v", signedParsed.AccountIdentifierSigners)
+	}
+
+	var hash TransactionIdentifierResponse
+	if err := post(t, srv, "/construction/hash", &ConstructionHashRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: combine.SignedTransaction,
+	}, &hash); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var submit TransactionIdentifierResponse
+	if err := post(t, srv, "/construction/submit", &ConstructionSubmitRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: combine.SignedTransaction,
+	}, &submit); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	if submit.TransactionIdentifier.Hash != hash.TransactionIdentifier.Hash {
+		t.Errorf("submitted %s, expected %s", submit.TransactionIdentifier.Hash, hash.TransactionIdentifier.Hash)
+	}
+	if len(node.pushed) != 1 || node.pushed[0].Message.Nonce != 7 {
+		t.Errorf("message not pushed with the node nonce")
+	}
+}
+
+func TestGuardedConstructionFlow(t *testing.T) {
+	tool := rosettaFilecoinLib.RosettaConstructionFilecoin{
+		Mainnet:  false,
+		FeeGuard: &rosettaFilecoinLib.FeeGuard{MaxFee: rosettaFilecoinLib.MustParseFIL("0.01"), MaxFeePercent: 1},
+	}
+	srv := httptest.NewServer(NewServer(tool, false, &fakeNode{}).Handler())
+	defer srv.Close()
+
+	// Preprocess constructs the transaction before its gas is known
+	var preprocess ConstructionPreprocessResponse
+	if err := post(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+	}, &preprocess); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var metadata ConstructionMetadataResponse
+	if err := post(t, srv, "/construction/metadata", &ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options:           preprocess.Options,
+	}, &metadata); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var payloads ConstructionPayloadsResponse
+	if err := post(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+		Metadata:          metadata.Metadata,
+	}, &payloads); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	sk, _ := hex.DecodeString(testSecretKey)
+	digest, _ := hex.DecodeString(payloads.Payloads[0].HexBytes)
+	sig, err := c.Sign(sk, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var combine ConstructionCombineResponse
+	if err := post(t, srv, "/construction/combine", &ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*Signature{{
+			SigningPayload: payloads.Payloads[0],
+			PublicKey:      &PublicKey{HexBytes: testPublicKey, CurveType: CurveSecp256k1},
+			SignatureType:  SignatureEcdsaRecovery,
+			HexBytes:       hex.EncodeToString(sig),
+		}},
+	}, &combine); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	// A max fee of 1 FIL exceeds the guard
+	var excessive map[string]interface{}
+	if err := convert(&rosettaFilecoinLib.TxMetadata{
+		Nonce:      7,
+		GasFeeCap:  rosettaFilecoinLib.MustParseFIL("0.000001"),
+		GasPremium: abi.NewTokenAmount(10),
+		GasLimit:   1000000,
+	}, &excessive); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := post(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+		Metadata:          excessive,
+	}, &payloads); err == nil {
+		t.Error("payloads should enforce the fee guard")
+	}
+}
+
+func TestOfflineServer(t *testing.T) {
+	srv := httptest.NewServer(NewServer(rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false}, false, nil).Handler())
+	defer srv.Close()
+
+	var metadata ConstructionMetadataResponse
+	err := post(t, srv, "/construction/metadata", &ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options:           map[string]interface{}{"sender": testFrom},
+	}, &metadata)
+	if err == nil || err.Code != ErrOffline.Code {
+		t.Errorf("metadata should not be available offline: %v", err)
+	}
+
+	var payloads ConstructionPayloadsResponse
+	if err := post(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        transferOperations(),
+		Metadata:          map[string]interface{}{"nonce": 1, "gas_fee_cap": "1", "gas_premium": "1", "gas_limit": 25000},
+	}, &payloads); err != nil {
+		t.Errorf("payloads should be available offline: %v", err.Details)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	srv := httptest.NewServer(NewServer(rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false}, false, nil).Handler())
+	defer srv.Close()
+
+	var derive ConstructionDeriveResponse
+	err := post(t, srv, "/construction/derive", &ConstructionDeriveRequest{
+		NetworkIdentifier: &NetworkIdentifier{Blockchain: Blockchain, Network: Mainnet},
+		PublicKey:         &PublicKey{HexBytes: testPublicKey, CurveType: CurveSecp256k1},
+	}, &derive)
+	if err == nil || err.Code != ErrInvalidNetwork.Code {
+		t.Errorf("mainnet request should be rejected by a testnet server: %v", err)
+	}
+
+	operations := transferOperations()
+	operations[1].Amount.Value = "1"
+	var preprocess ConstructionPreprocessResponse
+	err = post(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+	}, &preprocess)
+	if err == nil || err.Code != ErrUnsupportedOperations.Code {
+		t.Errorf("unbalanced operations should be rejected: %v", err)
+	}
+}
+
+func TestProposeOperations(t *testing.T) {
+	srv := httptest.NewServer(NewServer(rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false}, false, nil).Handler())
+	defer srv.Close()
+
+	operations := []*Operation{{
+		OperationIdentifier: OperationIdentifier{Index: 0},
+		Type:                OperationPropose,
+		Account:             &AccountIdentifier{Address: testFrom},
+		Amount:              &Amount{Value: "1000", Currency: FIL},
+		Metadata:            map[string]interface{}{"multisig": "t01002", "to": testTo},
+	}}
+
+	var payloads ConstructionPayloadsResponse
+	if err := post(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+		Metadata:          map[string]interface{}{"nonce": 1, "gas_fee_cap": "1", "gas_premium": "1", "gas_limit": 25000},
+	}, &payloads); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	var parsed ConstructionParseResponse
+	if err := post(t, srv, "/construction/parse", &ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Transaction:       payloads.UnsignedTransaction,
+	}, &parsed); err != nil {
+		t.Fatal(err.Details)
+	}
+	if !reflect.DeepEqual(parsed.Operations, operations) {
+		t.Errorf("parsed operations differ from the input")
+	}
+}
+
+// derivingTool is another implementation of the construction tool, it derives fixed addresses
+type derivingTool struct {
+	rosettaFilecoinLib.RosettaConstructionTool
+}
+
+func (derivingTool) DeriveFromPublicKey(publicKey []byte) (string, error) {
+	return "t01234", nil
+}
+
+func TestOtherImplementation(t *testing.T) {
+	tool := derivingTool{rosettaFilecoinLib.RosettaConstructionFilecoin{Mainnet: false}}
+	srv := httptest.NewServer(NewServer(tool, false, nil).Handler())
+	defer srv.Close()
+
+	var derive ConstructionDeriveResponse
+	if err := post(t, srv, "/construction/derive", &ConstructionDeriveRequest{
+		NetworkIdentifier: testNetwork,
+		PublicKey:         &PublicKey{HexBytes: testPublicKey, CurveType: CurveSecp256k1},
+	}, &derive); err != nil {
+		t.Fatal(err.Details)
+	}
+
+	if derive.AccountIdentifier == nil || derive.AccountIdentifier.Address != "t01234" {
+		t.Errorf("the server should use the given implementation: %+v", derive)
+	}
+}
@@ -0,0 +1,179 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package server
+
+// Models of the Rosetta Construction API (https://www.rosetta-api.org/docs/ConstructionApi.html)
+// Only the fields used by Filecoin are defined.
+
+// NetworkIdentifier specifies which network a request targets
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+// AccountIdentifier uniquely identifies an account
+type AccountIdentifier struct {
+	Address  string                 `json:"address"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Currency is composed of a canonical symbol and decimals
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int32  `json:"decimals"`
+}
+
+// Amount is a signed quantity of the smallest unit of a currency
+type Amount struct {
+	Value    string   `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+// OperationIdentifier uniquely identifies an operation within a transaction
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+// Operation is a balance-changing action of a transaction
+type Operation struct {
+	OperationIdentifier OperationIdentifier    `json:"operation_identifier"`
+	RelatedOperations   []OperationIdentifier  `json:"related_operations,omitempty"`
+	Type                string                 `json:"type"`
+	Status              string                 `json:"status,omitempty"`
+	Account             *AccountIdentifier     `json:"account,omitempty"`
+	Amount              *Amount                `json:"amount,omitempty"`
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// PublicKey is a public key with its curve
+type PublicKey struct {
+	HexBytes  string `json:"hex_bytes"`
+	CurveType string `json:"curve_type"`
+}
+
+// SigningPayload is the data an account must sign
+type SigningPayload struct {
+	AccountIdentifier *AccountIdentifier `json:"account_identifier,omitempty"`
+	HexBytes          string             `json:"hex_bytes"`
+	SignatureType     string             `json:"signature_type,omitempty"`
+}
+
+// Signature of a signing payload
+type Signature struct {
+	SigningPayload *SigningPayload `json:"signing_payload"`
+	PublicKey      *PublicKey      `json:"public_key"`
+	SignatureType  string          `json:"signature_type"`
+	HexBytes       string          `json:"hex_bytes"`
+}
+
+// TransactionIdentifier uniquely identifies a transaction
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+// Error is returned by every endpoint on failure
+type Error struct {
+	Code      int32                  `json:"code"`
+	Message   string                 `json:"message"`
+	Retriable bool                   `json:"retriable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type ConstructionDeriveRequest struct {
+	NetworkIdentifier *NetworkIdentifier     `json:"network_identifier"`
+	PublicKey         *PublicKey             `json:"public_key"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionDeriveResponse struct {
+	AccountIdentifier *AccountIdentifier     `json:"account_identifier"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionPreprocessRequest struct {
+	NetworkIdentifier *NetworkIdentifier     `json:"network_identifier"`
+	Operations        []*Operation           `json:"operations"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionPreprocessResponse struct {
+	Options            map[string]interface{} `json:"options,omitempty"`
+	RequiredPublicKeys []*AccountIdentifier   `json:"required_public_keys,omitempty"`
+}
+
+type ConstructionMetadataRequest struct {
+	NetworkIdentifier *NetworkIdentifier     `json:"network_identifier"`
+	Options           map[string]interface{} `json:"options,omitempty"`
+	PublicKeys        []*PublicKey           `json:"public_keys,omitempty"`
+}
+
+type ConstructionMetadataResponse struct {
+	Metadata     map[string]interface{} `json:"metadata"`
+	SuggestedFee []*Amount              `json:"suggested_fee,omitempty"`
+}
+
+type ConstructionPayloadsRequest struct {
+	NetworkIdentifier *NetworkIdentifier     `json:"network_identifier"`
+	Operations        []*Operation           `json:"operations"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	PublicKeys        []*PublicKey           `json:"public_keys,omitempty"`
+}
+
+type ConstructionPayloadsResponse struct {
+	UnsignedTransaction string            `json:"unsigned_transaction"`
+	Payloads            []*SigningPayload `json:"payloads"`
+}
+
+type ConstructionParseRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	Signed            bool               `json:"signed"`
+	Transaction       string             `json:"transaction"`
+}
+
+type ConstructionParseResponse struct {
+	Operations               []*Operation           `json:"operations"`
+	AccountIdentifierSigners []*AccountIdentifier   `json:"account_identifier_signers,omitempty"`
+	Metadata                 map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type ConstructionCombineRequest struct {
+	NetworkIdentifier   *NetworkIdentifier `json:"network_identifier"`
+	UnsignedTransaction string             `json:"unsigned_transaction"`
+	Signatures          []*Signature       `json:"signatures"`
+}
+
+type ConstructionCombineResponse struct {
+	SignedTransaction string `json:"signed_transaction"`
+}
+
+type ConstructionHashRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string             `json:"signed_transaction"`
+}
+
+type ConstructionSubmitRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string             `json:"signed_transaction"`
+}
+
+type TransactionIdentifierResponse struct {
+	TransactionIdentifier *TransactionIdentifier `json:"transaction_identifier"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
@@ -0,0 +1,310 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+)
+
+// Summary actions, besides the operation types
+const (
+	// SummaryCall is the action of the messages that are not described by operations, e.g. unknown methods
+	SummaryCall = "Call"
+	// SummaryProposal is the action of a multisig proposal of a call, wrapping the rendered proposed call
+	SummaryProposal = "Proposal"
+	// Calls of actor methods that are not described by operations
+	SummaryMinerWithdrawBalance      = "MinerWithdrawBalance"
+	SummaryMinerChangeWorkerAddress  = "MinerChangeWorkerAddress"
+	SummaryMinerChangePeerID         = "MinerChangePeerID"
+	SummaryPaychCreate               = "PaychCreate"
+	SummaryPaychUpdateChannelState   = "PaychUpdateChannelState"
+	SummaryPaychSettle               = "PaychSettle"
+	SummaryPaychCollect              = "PaychCollect"
+	SummaryVerifregAddVerifier       = "VerifregAddVerifier"
+	SummaryVerifregRemoveVerifier    = "VerifregRemoveVerifier"
+	SummaryVerifregAddVerifiedClient = "VerifregAddVerifiedClient"
+	// SummarySending is appended to the action of a call whose template does not show the value it carries
+	SummarySending = "Sending"
+	// SummaryTransaction is the template of the whole summary, wrapping the rendered action
+	SummaryTransaction = "Transaction"
+)
+
+// Summary fields, besides the operation metadata keys
+const (
+	SummaryFrom   = "from"
+	SummaryNonce  = "nonce"
+	SummaryMaxFee = "max_fee"
+	SummaryAmount = "amount"
+	SummaryMethod = "method"
+	// SummaryAction is the rendered action in the SummaryTransaction template
+	SummaryAction = "action"
+	// SummaryProposed is the rendered proposed call in the SummaryProposal template
+	SummaryProposed = "proposed"
+	// SummaryValue is the value of the message in the SummarySending template, only set when not zero
+	SummaryValue = "value"
+	// SummaryProposedValue is the value of the proposed call, rendered with the SummarySending template too
+	SummaryProposedValue = "proposed_value"
+)
+
+// SummaryTemplates are the English templates of the summaries, keyed by action. The {field} placeholders
+// are replaced by the fields of the summary. Translations must provide the same keys and placeholders.
+var SummaryTemplates = map[string]string{
+	OperationSend:                        "Send {amount} to {to}",
+	OperationPropose:                     "Propose on multisig {multisig}: send {amount} to {to}",
+	OperationSwapSigner:                  "Propose on multisig {multisig}: replace signer {old_signer} with {new_signer}",
+	OperationAddSigner:                   "Propose on multisig {multisig}: add signer {signer}, increase threshold: {increase}",
+	OperationRemoveSigner:                "Propose on multisig {multisig}: remove signer {signer}, decrease threshold: {decrease}",
+	OperationChangeNumApprovalsThreshold: "Propose on multisig {multisig}: change the approval threshold to {new_threshold}",
+	OperationLockBalance:                 "Propose on multisig {multisig}: lock {lock_amount} from epoch {start_epoch} over {unlock_duration} epochs",
+	OperationCreateMultisig: "Create a multisig of signers {signers} with threshold {threshold}, " +
+		"funded with {amount} vesting over {unlock_duration} epochs",
+	OperationApprove:                 "Approve transaction {txn_id} of multisig {multisig}",
+	OperationCancel:                  "Cancel transaction {txn_id} of multisig {multisig}",
+	OperationMarketAddBalance:        "Deposit {amount} into the storage market escrow of {provider_or_client}",
+	OperationMarketWithdrawBalance:   "Withdraw {amount} from the storage market escrow of {provider_or_client}",
+	SummaryCall:                      "Call method {method} of {to} with {amount}",
+	SummaryProposal:                  "Propose on multisig {multisig}: {proposed}",
+	SummaryMinerWithdrawBalance:      "Withdraw {amount_requested} from miner {to}",
+	SummaryMinerChangeWorkerAddress:  "Change the worker of miner {to} to {new_worker}, control addresses [{new_control_addrs}]",
+	SummaryMinerChangePeerID:         "Change the peer ID of miner {to} to {new_id}",
+	SummaryPaychCreate:               "Create a payment channel to {payee} funded with {amount}",
+	SummaryPaychUpdateChannelState:   "Redeem a voucher of {voucher_amount} on lane {lane} (nonce {voucher_nonce}) of payment channel {to}",
+	SummaryPaychSettle:               "Settle payment channel {to}",
+	SummaryPaychCollect:              "Collect payment channel {to}",
+	SummaryVerifregAddVerifier:       "Add verifier {address} with an allowance of {allowance}",
+	SummaryVerifregRemoveVerifier:    "Remove verifier {address}",
+	SummaryVerifregAddVerifiedClient: "Grant {allowance} of datacap to client {address}",
+	SummarySending:                   ", sending {value}",
+	SummaryTransaction:               "{action}, max fee {max_fee}, nonce {nonce}",
+}
+
+// TxSummary is a clear-text description of a transaction to review before signing it
+type TxSummary struct {
+	// Action is the operation type of the transaction, the action of its method call, SummaryProposal or
+	// SummaryCall, it selects the template
+	Action string `json:"action"`
+	// Proposed is the action of the proposed call when Action is SummaryProposal
+	Proposed string `json:"proposed,omitempty"`
+	// Fields are the values of the placeholders of the templates: the operation metadata or the call params,
+	// the amount, the value of a call whose template does not show it, the from address, the nonce and the max fee.
+	// Amounts are formatted in FIL.
+	Fields map[string]string `json:"fields"`
+	// Text is the summary rendered with SummaryTemplates
+	Text string `json:"text"`
+}
+
+// Render renders the summary with templates, e.g. a translation of SummaryTemplates
+func (s *TxSummary) Render(templates map[string]string) string {
+	pairs := make([]string, 0, 2*len(s.Fields))
+	for key, value := range s.Fields {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	action := replacer.Replace(templates[s.Action])
+	if _, ok := s.Fields[SummaryValue]; ok {
+		action += replacer.Replace(templates[SummarySending])
+	}
+	if s.Proposed != "" {
+		proposed := replacer.Replace(templates[s.Proposed])
+		if value, ok := s.Fields[SummaryProposedValue]; ok {
+			proposed += strings.NewReplacer("{"+SummaryValue+"}", value).Replace(templates[SummarySending])
+		}
+		action = strings.NewReplacer("{"+SummaryProposed+"}", proposed).Replace(action)
+	}
+	return strings.NewReplacer("{"+SummaryAction+"}", action).Replace(replacer.Replace(templates[SummaryTransaction]))
+}
+
+func (r RosettaConstructionFilecoin) SummarizeTx(unsignedTx string) (*TxSummary, error) {
+	msg, sm, err := DecodeTx(unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	if sm != nil {
+		msg = &sm.Message
+	}
+
+	summary := &TxSummary{
+		Fields: map[string]string{
+			SummaryFrom:   r.formatAddress(msg.From),
+			SummaryNonce:  strconv.FormatUint(msg.Nonce, 10),
+			SummaryMaxFee: FormatFIL(MaxFee(msg)),
+		},
+	}
+
+	operations, err := r.messageToOperations(msg)
+	if err != nil {
+		r.summarizeCall(summary, msg)
+	} else {
+		summarizeOperations(summary, operations)
+	}
+
+	summary.Text = summary.Render(SummaryTemplates)
+	return summary, nil
+}
+
+// summarizeOperations describes the operations of a transaction built by the Construct methods
+func summarizeOperations(summary *TxSummary, operations []Operation) {
+	op := operations[0]
+	summary.Action = op.Type
+
+	for key, value := range op.Metadata {
+		summary.Fields[key] = value
+	}
+
+	switch op.Type {
+	case OperationSend:
+		summary.Fields[MetadataTo] = operations[1].Account
+		summary.Fields[SummaryAmount] = FormatFIL(operations[1].Amount)
+
+	case OperationAddSigner, OperationRemoveSigner:
+		// The flags are only set in the metadata when true
+		for _, key := range []string{MetadataIncrease, MetadataDecrease} {
+			if _, ok := summary.Fields[key]; !ok {
+				summary.Fields[key] = strconv.FormatBool(false)
+			}
+		}
+
+	case OperationLockBalance:
+		if amount, err := big.FromString(op.Metadata[MetadataLockAmount]); err == nil {
+			summary.Fields[MetadataLockAmount] = FormatFIL(amount)
+		}
+
+	case OperationCreateMultisig:
+		summary.Fields[MetadataSigners] = strings.Join(strings.Split(op.Metadata[MetadataSigners], ","), ", ")
+		if _, ok := summary.Fields[MetadataUnlockDuration]; !ok {
+			summary.Fields[MetadataUnlockDuration] = "0"
+		}
+		summary.Fields[SummaryAmount] = FormatFIL(op.Amount)
+
+	default:
+		summary.Fields[SummaryAmount] = FormatFIL(op.Amount)
+	}
+}
+
+// summarizeCall describes a message that is not described by operations by its method, a multisig proposal
+// by its proposed call
+func (r RosettaConstructionFilecoin) summarizeCall(summary *TxSummary, msg *types.Message) {
+	call := r.parseCall(msg.To, msg.Value, msg.Method, msg.Params, actorsOf(msg.To))
+
+	if proposal, ok := call.Params["proposal"].(*ParsedCall); ok && call.Actor == ActorMultisig {
+		summary.Action = SummaryProposal
+		summary.Fields[MetadataMultisig] = call.To
+		summary.Proposed = describeCall(summary.Fields, proposal, SummaryProposedValue)
+		// The value of the Propose message itself leaves the signer too
+		setValueField(summary.Fields, SummaryValue, msg.Value)
+		return
+	}
+
+	summary.Action = describeCall(summary.Fields, call, SummaryValue)
+}
+
+// describeCall sets the fields of call and returns its action, SummaryCall for the methods without a template.
+// The value of a call whose template does not show it is set in the valueField.
+func describeCall(fields map[string]string, call *ParsedCall, valueField string) string {
+	fields[MetadataTo] = call.To
+	fields[SummaryAmount] = FormatFIL(call.Value)
+
+	switch call.Actor + "." + call.MethodName {
+	case ActorMiner + ".WithdrawBalance":
+		fields["amount_requested"] = formatAmountParam(call.Params["amount_requested"])
+		setValueField(fields, valueField, call.Value)
+		return SummaryMinerWithdrawBalance
+
+	case ActorMiner + ".ChangeWorkerAddress":
+		fields["new_worker"] = fmt.Sprint(call.Params["new_worker"])
+		controlAddrs, _ := call.Params["new_control_addrs"].([]string)
+		fields["new_control_addrs"] = strings.Join(controlAddrs, ", ")
+		setValueField(fields, valueField, call.Value)
+		return SummaryMinerChangeWorkerAddress
+
+	case ActorMiner + ".ChangePeerID":
+		fields["new_id"] = fmt.Sprint(call.Params["new_id"])
+		setValueField(fields, valueField, call.Value)
+		return SummaryMinerChangePeerID
+
+	case ActorInit + ".Exec":
+		constructor, ok := call.Params["constructor_params"].(map[string]interface{})
+		if ok && call.Params["code_cid"] == builtin.PaymentChannelActorCodeID.String() {
+			fields["payee"] = fmt.Sprint(constructor["to"])
+			return SummaryPaychCreate
+		}
+
+	case ActorPaych + ".UpdateChannelState":
+		if voucher, ok := call.Params["voucher"].(map[string]interface{}); ok {
+			fields["voucher_amount"] = formatAmountParam(voucher["amount"])
+			fields["lane"] = fmt.Sprint(voucher["lane"])
+			fields["voucher_nonce"] = fmt.Sprint(voucher["nonce"])
+			setValueField(fields, valueField, call.Value)
+			return SummaryPaychUpdateChannelState
+		}
+
+	case ActorPaych + ".Settle":
+		setValueField(fields, valueField, call.Value)
+		return SummaryPaychSettle
+
+	case ActorPaych + ".Collect":
+		setValueField(fields, valueField, call.Value)
+		return SummaryPaychCollect
+
+	case ActorVerifreg + ".AddVerifier", ActorVerifreg + ".AddVerifiedClient":
+		fields["address"] = fmt.Sprint(call.Params["address"])
+		if allowance, ok := call.Params["allowance"].(abi.StoragePower); ok {
+			fields["allowance"] = FormatDataCap(allowance)
+		}
+		setValueField(fields, valueField, call.Value)
+		if call.MethodName == "AddVerifier" {
+			return SummaryVerifregAddVerifier
+		}
+		return SummaryVerifregAddVerifiedClient
+
+	case ActorVerifreg + ".RemoveVerifier":
+		fields["address"] = fmt.Sprint(call.Params["address"])
+		setValueField(fields, valueField, call.Value)
+		return SummaryVerifregRemoveVerifier
+	}
+
+	method := strconv.FormatUint(call.Method, 10)
+	if call.MethodName != "" {
+		method = call.MethodName + " (" + method + ")"
+	}
+	fields[SummaryMethod] = method
+	return SummaryCall
+}
+
+// setValueField sets the field of a value that a template does not show, unless the value is zero
+func setValueField(fields map[string]string, field string, value abi.TokenAmount) {
+	if !value.NilOrZero() {
+		fields[field] = FormatFIL(value)
+	}
+}
+
+// formatAmountParam formats a decoded attoFIL param in FIL
+func formatAmountParam(param interface{}) string {
+	amount, ok := param.(abi.TokenAmount)
+	if !ok {
+		return fmt.Sprint(param)
+	}
+	return FormatFIL(amount)
+}
@@ -0,0 +1,74 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestSummaryTemplates(t *testing.T) {
+	r := &RosettaConstructionFilecoin{Mainnet: true}
+	tx, err := r.ConstructMultisigPayment(&MultisigPaymentRequest{
+		Multisig: "f0123",
+		From:     "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba",
+		Quantity: abi.NewTokenAmount(0),
+		Metadata: TxMetadata{
+			Nonce:      42,
+			GasFeeCap:  abi.NewTokenAmount(1000000),
+			GasPremium: abi.NewTokenAmount(1),
+			GasLimit:   3000000000,
+		},
+		Params: MultisigPaymentParams{To: "f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy", Quantity: MustParseFIL("12.5")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := r.SummarizeTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Propose on multisig f0123: send 12.5 FIL to f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy, max fee 0.003 FIL, nonce 42"
+	if summary.Text != expected {
+		t.Errorf("unexpected summary %s", summary.Text)
+	}
+
+	// Every action of the library has a template
+	for _, action := range []string{OperationSend, OperationPropose, OperationSwapSigner, OperationAddSigner,
+		OperationRemoveSigner, OperationChangeNumApprovalsThreshold, OperationLockBalance, OperationCreateMultisig,
+		OperationApprove, OperationCancel, OperationMarketAddBalance, OperationMarketWithdrawBalance, SummaryCall,
+		SummaryProposal, SummaryMinerWithdrawBalance, SummaryMinerChangeWorkerAddress, SummaryMinerChangePeerID,
+		SummaryPaychCreate, SummaryPaychUpdateChannelState, SummaryPaychSettle, SummaryPaychCollect,
+		SummaryVerifregAddVerifier, SummaryVerifregRemoveVerifier, SummaryVerifregAddVerifiedClient, SummarySending, SummaryTransaction} {
+		if SummaryTemplates[action] == "" {
+			t.Errorf("no template for %s", action)
+		}
+	}
+
+	french := map[string]string{
+		OperationPropose:   "Proposition sur le multisig {multisig} : envoyer {amount} à {to}",
+		SummaryTransaction: "{action} (frais max {max_fee}, nonce {nonce}, signataire {from})",
+	}
+
+	expected = "Proposition sur le multisig f0123 : envoyer 12.5 FIL à f17uoq6tp427uzv7fztkbsnn64iwotfrristwpryy " +
+		"(frais max 0.003 FIL, nonce 42, signataire f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba)"
+	if text := summary.Render(french); text != expected {
+		t.Errorf("unexpected translated summary %s", text)
+	}
+}
@@ -0,0 +1,255 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+// Unit is a FIL denomination
+type Unit struct {
+	Name string
+	// Decimals is the number of attoFIL decimals in one unit (1 unit = 10^Decimals attoFIL)
+	Decimals int
+}
+
+var (
+	FIL      = Unit{"FIL", 18}
+	MilliFIL = Unit{"milliFIL", 15}
+	MicroFIL = Unit{"microFIL", 12}
+	NanoFIL  = Unit{"nanoFIL", 9}
+	PicoFIL  = Unit{"picoFIL", 6}
+	FemtoFIL = Unit{"femtoFIL", 3}
+	AttoFIL  = Unit{"attoFIL", 0}
+)
+
+var units = []Unit{FIL, MilliFIL, MicroFIL, NanoFIL, PicoFIL, FemtoFIL, AttoFIL}
+
+// ParseUnit returns the denomination matching name (case insensitive)
+func ParseUnit(name string) (Unit, error) {
+	for _, unit := range units {
+		if strings.EqualFold(unit.Name, name) {
+			return unit, nil
+		}
+	}
+
+	return Unit{}, fmt.Errorf("unknown FIL unit '%s'", name)
+}
+
+// ParseFIL parses an amount such as "1.5 FIL", "200 nanoFIL", "3000 attoFIL" or "0.25"
+// into an exact attoFIL amount. Plain decimal strings are expressed in FIL.
+// Amounts cannot be negative, exceed the FIL supply or have a precision beyond one attoFIL.
+func ParseFIL(s string) (abi.TokenAmount, error) {
+	fields := strings.Fields(s)
+
+	unit := FIL
+	switch len(fields) {
+	case 1:
+	case 2:
+		var err error
+		unit, err = ParseUnit(fields[1])
+		if err != nil {
+			return abi.TokenAmount{}, err
+		}
+	default:
+		return abi.TokenAmount{}, fmt.Errorf("invalid FIL amount '%s'", s)
+	}
+
+	amount, err := parseDecimal(fields[0], unit.Decimals)
+	if err != nil {
+		return abi.TokenAmount{}, fmt.Errorf("invalid FIL amount '%s': %v", s, err)
+	}
+
+	return validateAmount("amount", amount)
+}
+
+// MustParseFIL is like ParseFIL but panics on invalid input. It is meant for constants and tests.
+func MustParseFIL(s string) abi.TokenAmount {
+	amount, err := ParseFIL(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// parseDecimal parses a non-negative decimal number and scales it by 10^decimals
+func parseDecimal(number string, decimals int) (big.Int, error) {
+	integer := number
+	fraction := ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		integer, fraction = number[:i], number[i+1:]
+	}
+
+	if integer == "" && fraction == "" {
+		return big.Int{}, fmt.Errorf("missing digits")
+	}
+
+	for _, part := range []string{integer, fraction} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return big.Int{}, fmt.Errorf("unexpected character '%c'", c)
+			}
+		}
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimals {
+		return big.Int{}, fmt.Errorf("precision exceeds one attoFIL")
+	}
+
+	digits := integer + fraction + strings.Repeat("0", decimals-len(fraction))
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return big.Zero(), nil
+	}
+
+	return big.FromString(digits)
+}
+
+// FormatFIL formats an attoFIL amount in FIL, e.g. "1.5 FIL"
+func FormatFIL(amount abi.TokenAmount) string {
+	return FormatAmount(amount, FIL)
+}
+
+// FormatAmount formats an attoFIL amount in the given unit, without trailing zeros, e.g. "200 nanoFIL"
+func FormatAmount(amount abi.TokenAmount, unit Unit) string {
+	if amount.Nil() {
+		amount = big.Zero()
+	}
+
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+		amount = amount.Neg()
+	}
+
+	digits := amount.String()
+	if len(digits) <= unit.Decimals {
+		digits = strings.Repeat("0", unit.Decimals-len(digits)+1) + digits
+	}
+
+	integer := digits[:len(digits)-unit.Decimals]
+	fraction := strings.TrimRight(digits[len(digits)-unit.Decimals:], "0")
+
+	if fraction == "" {
+		return fmt.Sprintf("%s%s %s", sign, integer, unit.Name)
+	}
+	return fmt.Sprintf("%s%s.%s %s", sign, integer, fraction, unit.Name)
+}
+
+// DataCapUnit is a binary unit of datacap, e.g. GiB
+type DataCapUnit struct {
+	Name string
+	// Bytes is the number of bytes in one unit
+	Bytes int64
+}
+
+var (
+	Byte = DataCapUnit{"B", 1}
+	KiB  = DataCapUnit{"KiB", 1 << 10}
+	MiB  = DataCapUnit{"MiB", 1 << 20}
+	GiB  = DataCapUnit{"GiB", 1 << 30}
+	TiB  = DataCapUnit{"TiB", 1 << 40}
+	PiB  = DataCapUnit{"PiB", 1 << 50}
+	EiB  = DataCapUnit{"EiB", 1 << 60}
+)
+
+// dataCapUnits are ordered from the largest, as FormatDataCap expects
+var dataCapUnits = []DataCapUnit{EiB, PiB, TiB, GiB, MiB, KiB, Byte}
+
+// ParseDataCapUnit returns the datacap unit matching name (case insensitive)
+func ParseDataCapUnit(name string) (DataCapUnit, error) {
+	for _, unit := range dataCapUnits {
+		if strings.EqualFold(unit.Name, name) {
+			return unit, nil
+		}
+	}
+
+	return DataCapUnit{}, fmt.Errorf("unknown datacap unit '%s'", name)
+}
+
+// ParseDataCap parses a datacap such as "1 TiB", "1.5 GiB" or "1048576" into an exact number of bytes.
+// Plain numbers are expressed in bytes. Datacaps cannot be negative or have a precision beyond one byte.
+func ParseDataCap(s string) (abi.StoragePower, error) {
+	fields := strings.Fields(s)
+
+	unit := Byte
+	switch len(fields) {
+	case 1:
+	case 2:
+		var err error
+		unit, err = ParseDataCapUnit(fields[1])
+		if err != nil {
+			return abi.StoragePower{}, err
+		}
+	default:
+		return abi.StoragePower{}, fmt.Errorf("invalid datacap '%s'", s)
+	}
+
+	// Scale by 10^decimals first so that the fraction is exact, then by the unit
+	number := fields[0]
+	decimals := 0
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		decimals = len(strings.TrimRight(number[i+1:], "0"))
+	}
+
+	scaled, err := parseDecimal(number, decimals)
+	if err != nil {
+		return abi.StoragePower{}, fmt.Errorf("invalid datacap '%s': %v", s, err)
+	}
+
+	divisor := big.NewInt(1)
+	for i := 0; i < decimals; i++ {
+		divisor = big.Mul(divisor, big.NewInt(10))
+	}
+
+	size := big.Mul(scaled, big.NewInt(unit.Bytes))
+	if big.Mod(size, divisor).Sign() != 0 {
+		return abi.StoragePower{}, fmt.Errorf("invalid datacap '%s': precision exceeds one byte", s)
+	}
+
+	return big.Div(size, divisor), nil
+}
+
+// MustParseDataCap is like ParseDataCap but panics on invalid input. It is meant for constants and tests.
+func MustParseDataCap(s string) abi.StoragePower {
+	dataCap, err := ParseDataCap(s)
+	if err != nil {
+		panic(err)
+	}
+	return dataCap
+}
+
+// FormatDataCap formats a datacap in the largest unit dividing it, e.g. "1 TiB" or "1536 GiB"
+func FormatDataCap(dataCap abi.StoragePower) string {
+	if dataCap.Nil() {
+		dataCap = big.Zero()
+	}
+
+	unit := Byte
+	for _, u := range dataCapUnits {
+		if dataCap.Sign() != 0 && big.Mod(dataCap, big.NewInt(u.Bytes)).Sign() == 0 {
+			unit = u
+			break
+		}
+	}
+
+	return fmt.Sprintf("%s %s", big.Div(dataCap, big.NewInt(unit.Bytes)), unit.Name)
+}
@@ -0,0 +1,170 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+func TestParseFIL(t *testing.T) {
+	cases := map[string]string{
+		"1.5 FIL":                  "1500000000000000000",
+		"1.5":                      "1500000000000000000",
+		"200 nanoFIL":              "200000000000",
+		"3000 attoFIL":             "3000",
+		"3000 attofil":             "3000",
+		"0.000000000000000001 FIL": "1",
+		"1.000 attoFIL":            "1",
+		".5 milliFIL":              "500000000000000",
+		"7 microFIL":               "7000000000000",
+		"42 picoFIL":               "42000000",
+		"0":                        "0",
+		"2000 FIL":                 "2000000000000000000000",
+	}
+
+	for input, expected := range cases {
+		amount, err := ParseFIL(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		if amount.String() != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, amount)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"FIL",
+		"1.5 FILL",
+		"0.0000000000000000001 FIL",
+		"1.5 attoFIL",
+		"-1 FIL",
+		"1e18",
+		"1 2 FIL",
+		"3000000000 FIL",
+	}
+
+	for _, input := range invalid {
+		if _, err := ParseFIL(input); err == nil {
+			t.Errorf("%s: should fail", input)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	cases := []struct {
+		amount   string
+		unit     Unit
+		expected string
+	}{
+		{"1500000000000000000", FIL, "1.5 FIL"},
+		{"1", FIL, "0.000000000000000001 FIL"},
+		{"0", FIL, "0 FIL"},
+		{"200000000000", NanoFIL, "200 nanoFIL"},
+		{"3000", AttoFIL, "3000 attoFIL"},
+		{"-2500", FemtoFIL, "-2.5 femtoFIL"},
+		{"12000000000000000000000", FIL, "12000 FIL"},
+	}
+
+	for _, c := range cases {
+		formatted := FormatAmount(big.MustFromString(c.amount), c.unit)
+		if formatted != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, formatted)
+		}
+
+		if c.amount[0] == '-' {
+			continue
+		}
+
+		parsed, err := ParseFIL(formatted)
+		if err != nil || parsed.String() != c.amount {
+			t.Errorf("%s does not round trip: %s %v", formatted, parsed, err)
+		}
+	}
+
+	if FormatFIL(big.Int{}) != "0 FIL" {
+		t.Error("unset amount should format as zero")
+	}
+}
+
+func TestParseDataCap(t *testing.T) {
+	cases := map[string]string{
+		"1 TiB":     "1099511627776",
+		"1 tib":     "1099511627776",
+		"1.5 GiB":   "1610612736",
+		"512 MiB":   "536870912",
+		".25 KiB":   "256",
+		"1048576":   "1048576",
+		"3 B":       "3",
+		"2.000 PiB": "2251799813685248",
+		"0":         "0",
+	}
+
+	for input, expected := range cases {
+		dataCap, err := ParseDataCap(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		if dataCap.String() != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, dataCap)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"TiB",
+		"1 TB",
+		"0.5 B",
+		"0.1 KiB",
+		"-1 GiB",
+		"1e9",
+		"1 2 GiB",
+	}
+
+	for _, input := range invalid {
+		if _, err := ParseDataCap(input); err == nil {
+			t.Errorf("%s: should fail", input)
+		}
+	}
+}
+
+func TestFormatDataCap(t *testing.T) {
+	cases := map[string]string{
+		"1099511627776": "1 TiB",
+		"1610612736":    "1536 MiB",
+		"1048576":       "1 MiB",
+		"1000":          "1000 B",
+		"0":             "0 B",
+	}
+
+	for input, expected := range cases {
+		formatted := FormatDataCap(big.MustFromString(input))
+		if formatted != expected {
+			t.Errorf("expected %s, got %s", expected, formatted)
+		}
+
+		parsed, err := ParseDataCap(formatted)
+		if err != nil || parsed.String() != input {
+			t.Errorf("%s does not round trip: %s %v", formatted, parsed, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+)
+
+// validateAllowance checks that a datacap allowance is accepted by the verified registry actor
+func validateAllowance(allowance abi.StoragePower) error {
+	if allowance.Nil() || allowance.LessThan(verifreg.MinVerifiedDealSize) {
+		return fmt.Errorf("allowance must be at least the minimum verified deal size (%s)", FormatDataCap(verifreg.MinVerifiedDealSize))
+	}
+	return nil
+}
+
+func (r RosettaConstructionFilecoin) ConstructVerifregAddVerifier(request *VerifregAllowanceRequest) (string, error) {
+	if err := validateAllowance(request.Params.Allowance); err != nil {
+		return "", err
+	}
+
+	verifier, err := r.parseAddress(request.Params.Address)
+	if err != nil {
+		return "", err
+	}
+
+	addParams := &verifreg.AddVerifierParams{
+		Address:   verifier,
+		Allowance: request.Params.Allowance,
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.VerifiedRegistryActorAddr), request.From, request.Multisig,
+		big.Zero(), &request.Metadata, builtin.MethodsVerifiedRegistry.AddVerifier, addParams)
+}
+
+func (r RosettaConstructionFilecoin) ConstructVerifregRemoveVerifier(request *VerifregRemoveVerifierRequest) (string, error) {
+	verifier, err := r.parseAddress(request.Params.Address)
+	if err != nil {
+		return "", err
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.VerifiedRegistryActorAddr), request.From, request.Multisig,
+		big.Zero(), &request.Metadata, builtin.MethodsVerifiedRegistry.RemoveVerifier, &verifier)
+}
+
+func (r RosettaConstructionFilecoin) ConstructVerifregAddVerifiedClient(request *VerifregAllowanceRequest) (string, error) {
+	if err := validateAllowance(request.Params.Allowance); err != nil {
+		return "", err
+	}
+
+	client, err := r.parseAddress(request.Params.Address)
+	if err != nil {
+		return "", err
+	}
+
+	addParams := &verifreg.AddVerifiedClientParams{
+		Address:   client,
+		Allowance: request.Params.Allowance,
+	}
+
+	return r.constructActorCall(r.formatAddress(builtin.VerifiedRegistryActorAddr), request.From, request.Multisig,
+		big.Zero(), &request.Metadata, builtin.MethodsVerifiedRegistry.AddVerifiedClient, addParams)
+}
@@ -0,0 +1,76 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+)
+
+// MultisigVesting is the vesting schedule of a multisig: InitialBalance unlocks linearly over UnlockDuration epochs
+// from StartEpoch. The fields are named as in the multisig actor state, so the State returned by
+// Lotus StateReadState can be decoded into it.
+type MultisigVesting struct {
+	InitialBalance abi.TokenAmount
+	StartEpoch     int64
+	UnlockDuration int64
+}
+
+// LockedAmount returns the amount still locked at epoch, rounded as the multisig actor does
+func (v MultisigVesting) LockedAmount(epoch int64) abi.TokenAmount {
+	if v.InitialBalance.Nil() || v.UnlockDuration == 0 {
+		return big.Zero()
+	}
+
+	st := multisig.State{
+		InitialBalance: v.InitialBalance,
+		StartEpoch:     abi.ChainEpoch(v.StartEpoch),
+		UnlockDuration: abi.ChainEpoch(v.UnlockDuration),
+	}
+	return st.AmountLocked(abi.ChainEpoch(epoch - v.StartEpoch))
+}
+
+// SpendableAmount returns the part of balance that can be spent at epoch, zero if it is all locked
+func (v MultisigVesting) SpendableAmount(balance abi.TokenAmount, epoch int64) abi.TokenAmount {
+	spendable := big.Sub(balance, v.LockedAmount(epoch))
+	if spendable.LessThan(big.Zero()) {
+		return big.Zero()
+	}
+	return spendable
+}
+
+// CheckSpend returns an error when a proposal spending amount out of balance, applied at epoch,
+// would fail on-chain because of the vesting schedule or an insufficient balance
+func (v MultisigVesting) CheckSpend(balance abi.TokenAmount, amount abi.TokenAmount, epoch int64) error {
+	if amount.LessThan(big.Zero()) {
+		return fmt.Errorf("amount %s is negative", amount)
+	}
+
+	if balance.LessThan(amount) {
+		return fmt.Errorf("balance %s is less than amount %s", balance, amount)
+	}
+
+	spendable := v.SpendableAmount(balance, epoch)
+	if spendable.LessThan(amount) {
+		return fmt.Errorf("amount %s exceeds the spendable balance %s at epoch %d, %s is locked",
+			amount, spendable, epoch, v.LockedAmount(epoch))
+	}
+
+	return nil
+}
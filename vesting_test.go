@@ -0,0 +1,86 @@
+/*******************************************************************************
+*   (c) 2020 Zondax GmbH
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+********************************************************************************/
+package rosettaFilecoinLib
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func TestMultisigVesting(t *testing.T) {
+	vesting := MultisigVesting{
+		InitialBalance: abi.NewTokenAmount(1000),
+		StartEpoch:     100,
+		UnlockDuration: 10,
+	}
+	balance := abi.NewTokenAmount(1500)
+
+	cases := []struct {
+		epoch     int64
+		locked    int64
+		spendable int64
+	}{
+		{50, 1000, 500},
+		{100, 1000, 500},
+		{104, 600, 900},
+		{110, 0, 1500},
+		{200, 0, 1500},
+	}
+
+	for _, c := range cases {
+		if locked := vesting.LockedAmount(c.epoch); !locked.Equals(abi.NewTokenAmount(c.locked)) {
+			t.Errorf("epoch %d: expected %d locked, got %s", c.epoch, c.locked, locked)
+		}
+		if spendable := vesting.SpendableAmount(balance, c.epoch); !spendable.Equals(abi.NewTokenAmount(c.spendable)) {
+			t.Errorf("epoch %d: expected %d spendable, got %s", c.epoch, c.spendable, spendable)
+		}
+	}
+
+	if err := vesting.CheckSpend(balance, abi.NewTokenAmount(900), 104); err != nil {
+		t.Error(err)
+	}
+	if err := vesting.CheckSpend(balance, abi.NewTokenAmount(901), 104); err == nil {
+		t.Error("spending a locked amount should fail")
+	}
+	if err := vesting.CheckSpend(balance, abi.NewTokenAmount(1501), 200); err == nil {
+		t.Error("spending more than the balance should fail")
+	}
+
+	// Part of the initial balance may have been spent, nothing is spendable while it stays below the locked amount
+	if spendable := vesting.SpendableAmount(abi.NewTokenAmount(500), 104); !spendable.IsZero() {
+		t.Errorf("expected nothing spendable, got %s", spendable)
+	}
+
+	if locked := (MultisigVesting{}).LockedAmount(0); !locked.IsZero() {
+		t.Error("a multisig without vesting should not lock anything")
+	}
+}
+
+func TestMultisigVestingFromState(t *testing.T) {
+	state := `{"Signers":["t01001"],"NumApprovalsThreshold":1,"NextTxnID":0,
+		"InitialBalance":"1000","StartEpoch":100,"UnlockDuration":10,"PendingTxns":{"/":"bafy2bzace"}}`
+
+	var vesting MultisigVesting
+	if err := json.Unmarshal([]byte(state), &vesting); err != nil {
+		t.Fatal(err)
+	}
+
+	if !vesting.LockedAmount(105).Equals(abi.NewTokenAmount(500)) {
+		t.Errorf("unexpected vesting %+v", vesting)
+	}
+}